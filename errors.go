@@ -0,0 +1,209 @@
+package jsons
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/qjebbs/go-jsons/internal/interpolate"
+	"github.com/qjebbs/go-jsons/internal/merge"
+	"github.com/qjebbs/go-jsons/internal/ordered"
+	"github.com/qjebbs/go-jsons/internal/patch"
+	"github.com/qjebbs/go-jsons/internal/pointer"
+)
+
+// ErrTypeMismatch is the error of merging values of different types,
+// which can be checked with errors.Is.
+var ErrTypeMismatch = merge.ErrTypeMismatch
+
+// ErrUndefinedReference is the error of interpolating undefined
+// variables or values, see WithInterpolation.
+var ErrUndefinedReference = interpolate.ErrUndefined
+
+// ErrReferenceCycle is the error of interpolating values referring
+// to themselves, see WithInterpolation.
+var ErrReferenceCycle = interpolate.ErrCycle
+
+// ErrUnknownField is the error of fields not found in the destination
+// of MergeInto, see WithDisallowUnknownFields.
+var ErrUnknownField = errors.New("unknown field")
+
+// MergeError is the error occurred when loading or merging an input.
+type MergeError struct {
+	// Input is the file path of the input, or "inputs[i]" for the
+	// i-th non-file input, see Provenance for details.
+	Input string
+	// Path is the JSON pointer of the value failed to merge,
+	// empty for errors not related to a value.
+	Path string
+	// Expected and Incoming are the types of the existing and
+	// incoming values, when the error is ErrTypeMismatch.
+	Expected, Incoming string
+	// Line and Column are the 1-based position in the input, when the
+	// loader can provide it, otherwise 0.
+	//
+	// Errors returned by custom loaders can provide the position by
+	// implementing interface{ Position() (line, column int) }.
+	Line, Column int
+	// Err is the underlying error
+	Err error
+}
+
+// Error implements the error interface.
+func (e *MergeError) Error() string {
+	var b strings.Builder
+	if e.Input != "" {
+		b.WriteString(e.Input)
+		if e.Line > 0 {
+			fmt.Fprintf(&b, ":%d:%d", e.Line, e.Column)
+		}
+		b.WriteString(": ")
+	}
+	e.writeDetail(&b)
+	return b.String()
+}
+
+// writeDetail writes the error without the input and position to b
+func (e *MergeError) writeDetail(b *strings.Builder) {
+	if e.Path != "" {
+		fmt.Fprintf(b, "%s: ", e.Path)
+	}
+	b.WriteString(e.Err.Error())
+	if e.Expected != "" {
+		fmt.Fprintf(b, ", expect %s, incoming %s", e.Expected, e.Incoming)
+	}
+}
+
+// Unwrap returns the underlying error.
+func (e *MergeError) Unwrap() error {
+	return e.Err
+}
+
+// FormatError is the error of loading an input of unknown format,
+// when none of the loaders tried succeeded, see WithLoaderDetect.
+//
+// It unwraps to the error of the first loader tried, who is the most
+// likely format of the input, and the *MergeError returning it has
+// the same Line and Column.
+type FormatError struct {
+	// Formats are the formats tried in order, and Errors are their
+	// errors, with the line and column in the input if known.
+	Formats []Format
+	Errors  []*MergeError
+	// Rejected are the formats whose detect functions rejected
+	// the input, sorted by name.
+	Rejected []Format
+}
+
+// Error implements the error interface.
+func (e *FormatError) Error() string {
+	var b strings.Builder
+	b.WriteString("tried all formats but failed: ")
+	for i, err := range e.Errors {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		fmt.Fprintf(&b, "[%s] ", e.Formats[i])
+		if err.Line > 0 {
+			fmt.Fprintf(&b, "%d:%d: ", err.Line, err.Column)
+		}
+		err.writeDetail(&b)
+	}
+	for i, format := range e.Rejected {
+		if i > 0 || len(e.Errors) > 0 {
+			b.WriteString("; ")
+		}
+		fmt.Fprintf(&b, "[%s] format not detected", format)
+	}
+	return b.String()
+}
+
+// Unwrap returns the error of the first loader tried.
+func (e *FormatError) Unwrap() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e.Errors[0]
+}
+
+// newMergeError converts err occurred on input into a *MergeError,
+// the Input is filled if not yet, with the tracked source of the
+// incoming value if input is empty.
+func newMergeError(input string, err error) error {
+	var me *MergeError
+	if errors.As(err, &me) {
+		if me.Input == "" {
+			me.Input = input
+		}
+		return me
+	}
+	me = &MergeError{Input: input, Err: err}
+	var (
+		merr *merge.Error
+		perr *patch.Error
+		ierr *interpolate.Error
+	)
+	switch {
+	case errors.As(err, &merr):
+		if me.Input == "" {
+			me.Input = merr.Source
+		}
+		me.Path = pointer.Format(merr.Path)
+		me.Expected = merr.Expected
+		me.Incoming = merr.Incoming
+		me.Err = merr.Err
+	case errors.As(err, &perr):
+		me.Path = perr.Path
+	case errors.As(err, &ierr):
+		me.Path = pointer.Format(ierr.Path)
+		me.Err = ierr.Err
+	}
+	return me
+}
+
+// positioner is the error who knows the position in the input
+type positioner interface {
+	Position() (line, column int)
+}
+
+// newLoadError converts err occurred on loading data into a *MergeError,
+// with the position filled if possible.
+func newLoadError(input string, data []byte, err error) error {
+	me := &MergeError{Input: input, Err: err}
+	var (
+		pos      positioner
+		syntax   *json.SyntaxError
+		typeErr  *json.UnmarshalTypeError
+		limitErr *ordered.LimitError
+	)
+	switch {
+	case errors.As(err, &limitErr):
+		me.Path = pointer.Format(limitErr.Path)
+		me.Line, me.Column = position(data, limitErr.Offset)
+		me.Err = limitErr.Err
+	case errors.As(err, &pos):
+		me.Line, me.Column = pos.Position()
+	case errors.As(err, &syntax):
+		me.Line, me.Column = position(data, syntax.Offset)
+	case errors.As(err, &typeErr):
+		me.Line, me.Column = position(data, typeErr.Offset)
+	}
+	return me
+}
+
+// position returns the 1-based line and column of the byte
+// just before offset, where the decoder stopped.
+func position(data []byte, offset int64) (line, column int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	if offset > 0 {
+		offset--
+	}
+	before := data[:offset]
+	line = bytes.Count(before, []byte{'\n'}) + 1
+	column = int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
@@ -0,0 +1,208 @@
+package jsons_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/qjebbs/go-jsons"
+)
+
+func TestMergeErrorTypeMismatch(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.json")
+	err := os.WriteFile(a, []byte(`{"outbounds":[{"settings":{"port":1}}]}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := []byte(`{"outbounds":[{"settings":{"port":"2"}}]}`)
+	m := jsons.NewMerger(
+		jsons.WithArrayStrategy("/outbounds", jsons.ArrayMergeByIndex),
+	)
+	_, err = m.Merge(a, b)
+	var me *jsons.MergeError
+	if !errors.As(err, &me) {
+		t.Fatalf("want *jsons.MergeError, got %T: %v", err, err)
+	}
+	want := &jsons.MergeError{
+		Input:    "inputs[1]",
+		Path:     "/outbounds/0/settings/port",
+		Expected: "number",
+		Incoming: "string",
+		Err:      jsons.ErrTypeMismatch,
+	}
+	if *me != *want {
+		t.Errorf("want:\n%#v\ngot:\n%#v", want, me)
+	}
+	if !errors.Is(err, jsons.ErrTypeMismatch) {
+		t.Errorf("want errors.Is ErrTypeMismatch")
+	}
+	wantMsg := "inputs[1]: /outbounds/0/settings/port: type mismatch, expect number, incoming string"
+	if err.Error() != wantMsg {
+		t.Errorf("want %q, got %q", wantMsg, err.Error())
+	}
+}
+
+func TestMergeErrorMergeBy(t *testing.T) {
+	a := []byte(`{"a":[{"tag":"x","value":1}]}`)
+	b := []byte(`{"a":[{"tag":"x","value":false}]}`)
+	m := jsons.NewMerger(jsons.WithMergeBy("tag"))
+	_, err := m.Merge(a, b)
+	var me *jsons.MergeError
+	if !errors.As(err, &me) {
+		t.Fatalf("want *jsons.MergeError, got %T: %v", err, err)
+	}
+	if me.Input != "inputs[1]" || me.Path != "/a/0/value" ||
+		me.Expected != "number" || me.Incoming != "boolean" {
+		t.Errorf("unexpected error: %#v", me)
+	}
+	// in a nested array
+	a = []byte(`{"r":{"rules":[{"tag":"x","v":1}]}}`)
+	b = []byte(`{"r":{"rules":[{"tag":"x","v":"a"}]}}`)
+	got, err := m.Merge(a, b)
+	if !errors.As(err, &me) {
+		t.Fatalf("want *jsons.MergeError, got %v: %s", err, got)
+	}
+	if !errors.Is(err, jsons.ErrTypeMismatch) || me.Input != "inputs[1]" || me.Path != "/r/rules/0/v" {
+		t.Errorf("unexpected error: %#v", me)
+	}
+}
+
+func TestMergeErrorPosition(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.json")
+	err := os.WriteFile(a, []byte("{\n  \"a\": 1,\n  \"b\": ]\n}"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = jsons.Merge(a)
+	var me *jsons.MergeError
+	if !errors.As(err, &me) {
+		t.Fatalf("want *jsons.MergeError, got %T: %v", err, err)
+	}
+	if me.Input != a || me.Line != 3 || me.Column != 8 {
+		t.Errorf("want %s:3:8, got %s:%d:%d", a, me.Input, me.Line, me.Column)
+	}
+	if !strings.HasPrefix(err.Error(), a+":3:8: ") {
+		t.Errorf("unexpected message: %s", err)
+	}
+}
+
+type posError struct{}
+
+func (posError) Error() string                { return "bad input" }
+func (posError) Position() (line, column int) { return 2, 3 }
+
+func TestMergeErrorCustomPosition(t *testing.T) {
+	m := jsons.NewMerger()
+	err := m.RegisterOrderedLoader("custom", []string{".custom"}, func(b []byte) (*jsons.OrderedMap, error) {
+		return nil, fmt.Errorf("custom: %w", posError{})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.MergeAs("custom", [][]byte{[]byte(`a`)})
+	var me *jsons.MergeError
+	if !errors.As(err, &me) {
+		t.Fatalf("want *jsons.MergeError, got %T: %v", err, err)
+	}
+	if me.Input != "inputs[0][0]" || me.Line != 2 || me.Column != 3 {
+		t.Errorf("unexpected error: %#v", me)
+	}
+}
+
+func TestMergeErrorAllFormats(t *testing.T) {
+	_, err := jsons.Merge([]byte(`{}`), strings.NewReader(`a`))
+	var me *jsons.MergeError
+	if !errors.As(err, &me) {
+		t.Fatalf("want *jsons.MergeError, got %T: %v", err, err)
+	}
+	if me.Input != "inputs[1]" || !strings.Contains(me.Err.Error(), "tried all formats but failed") {
+		t.Errorf("unexpected error: %v", me)
+	}
+}
+
+func TestMergeErrorAllFormatsPosition(t *testing.T) {
+	_, err := jsons.Merge([]byte("{\n\"a\":1\n\"b\":2}"))
+	var me *jsons.MergeError
+	if !errors.As(err, &me) {
+		t.Fatalf("want *jsons.MergeError, got %T: %v", err, err)
+	}
+	if me.Input != "inputs[0]" || me.Line != 3 || me.Column != 1 {
+		t.Errorf("want inputs[0]:3:1, got %s:%d:%d", me.Input, me.Line, me.Column)
+	}
+	var fe *jsons.FormatError
+	if !errors.As(err, &fe) {
+		t.Fatalf("want *jsons.FormatError, got %T: %v", err, err)
+	}
+	if len(fe.Errors) != 2 || fe.Formats[0] != jsons.FormatJSON || fe.Formats[1] != jsons.FormatJSONC {
+		t.Fatalf("unexpected formats tried: %v", fe.Formats)
+	}
+	if len(fe.Rejected) != 1 || fe.Rejected[0] != jsons.FormatJSONPatch {
+		t.Errorf("unexpected formats rejected: %v", fe.Rejected)
+	}
+	for i, e := range fe.Errors {
+		if e.Line != 3 || e.Column != 1 {
+			t.Errorf("%s: want 3:1, got %d:%d", fe.Formats[i], e.Line, e.Column)
+		}
+	}
+	var syntax *json.SyntaxError
+	if !errors.As(err, &syntax) {
+		t.Errorf("want *json.SyntaxError, got %v", err)
+	}
+}
+
+func TestMergeErrorPatch(t *testing.T) {
+	_, err := jsons.Merge([]byte(`{}`), []byte(`[{"op":"remove","path":"/a"}]`))
+	var me *jsons.MergeError
+	if !errors.As(err, &me) {
+		t.Fatalf("want *jsons.MergeError, got %T: %v", err, err)
+	}
+	if me.Input != "inputs[1]" || me.Path != "/a" {
+		t.Errorf("unexpected error: %v", me)
+	}
+}
+
+func TestMergeErrorJSONCPosition(t *testing.T) {
+	_, err := jsons.NewMerger().MergeAs(jsons.FormatJSONC, []byte("{\n  // comment\n  \"a\": ]\n}"))
+	var me *jsons.MergeError
+	if !errors.As(err, &me) {
+		t.Fatalf("want *jsons.MergeError, got %T: %v", err, err)
+	}
+	if me.Line != 3 || me.Column != 8 {
+		t.Errorf("want 3:8, got %d:%d", me.Line, me.Column)
+	}
+	_, err = jsons.NewMerger().MergeAs(jsons.FormatJSONC, []byte("{\n  /* comment\n}"))
+	if !errors.As(err, &me) {
+		t.Fatalf("want *jsons.MergeError, got %T: %v", err, err)
+	}
+	if me.Line != 2 || me.Column != 3 {
+		t.Errorf("want 2:3, got %d:%d", me.Line, me.Column)
+	}
+}
+
+func TestMergeErrorInterpolation(t *testing.T) {
+	m := jsons.NewMerger(jsons.WithInterpolation(func(string) (string, bool) {
+		return "", false
+	}))
+	a := []byte(`{"log":{"level":"info"}}`)
+	b := []byte(`{"log":{"level":"${LEVEL}"}}`)
+	_, err := m.Merge(a, b)
+	var me *jsons.MergeError
+	if !errors.As(err, &me) {
+		t.Fatalf("want *jsons.MergeError, got %T: %v", err, err)
+	}
+	if !errors.Is(err, jsons.ErrUndefinedReference) || me.Path != "/log/level" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// the input is known with provenance tracked
+	_, _, err = m.MergeWithProvenance(a, b)
+	want := "inputs[1]: /log/level: undefined reference: LEVEL"
+	if err == nil || err.Error() != want {
+		t.Errorf("want %q, got %v", want, err)
+	}
+}
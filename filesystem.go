@@ -0,0 +1,123 @@
+package jsons
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// FSPath is an input of the file at Path of FS.
+//
+// If FS is nil, the file system of the merger is used,
+// see WithFS for details.
+type FSPath struct {
+	FS   fs.FS
+	Path string
+}
+
+// fileSystem is the file system to load files from
+type fileSystem interface {
+	Open(name string) (fs.File, error)
+	Stat(name string) (fs.FileInfo, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+	WalkDir(root string, fn fs.WalkDirFunc) error
+	// Join joins the path elements
+	Join(elem ...string) string
+	// Resolve resolves name relative to the directory of file base,
+	// or the current directory if base is empty.
+	Resolve(base, name string) string
+	// Rel returns the slash-separated path of target relative to base,
+	// where target is a path walked from base.
+	Rel(base, target string) (string, error)
+	// FromSlash converts the slash-separated pattern to a path.
+	FromSlash(pattern string) string
+	// ToSlash converts the path to a slash-separated one.
+	ToSlash(name string) string
+}
+
+// newFileSystem returns the fileSystem of fsys,
+// or the local file system if fsys is nil.
+func newFileSystem(fsys fs.FS) fileSystem {
+	if fsys == nil {
+		return osFileSystem{}
+	}
+	return ioFileSystem{fsys}
+}
+
+// fileSystemOf returns the fileSystem of fsys, or def if fsys is nil.
+func fileSystemOf(fsys fs.FS, def fileSystem) fileSystem {
+	if fsys == nil {
+		return def
+	}
+	return ioFileSystem{fsys}
+}
+
+// osFileSystem is the local file system
+type osFileSystem struct{}
+
+func (osFileSystem) Open(name string) (fs.File, error)          { return os.Open(name) }
+func (osFileSystem) Stat(name string) (fs.FileInfo, error)      { return os.Stat(name) }
+func (osFileSystem) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+func (osFileSystem) Join(elem ...string) string                 { return filepath.Join(elem...) }
+func (osFileSystem) FromSlash(pattern string) string            { return filepath.FromSlash(pattern) }
+func (osFileSystem) ToSlash(name string) string                 { return filepath.ToSlash(name) }
+
+func (osFileSystem) Resolve(base, name string) string {
+	if base == "" || filepath.IsAbs(name) {
+		return filepath.Clean(name)
+	}
+	return filepath.Join(filepath.Dir(base), name)
+}
+
+func (osFileSystem) WalkDir(root string, fn fs.WalkDirFunc) error {
+	return filepath.WalkDir(root, fn)
+}
+
+func (osFileSystem) Rel(base, target string) (string, error) {
+	rel, err := filepath.Rel(base, target)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// ioFileSystem is the file system of fs.FS, whose paths are
+// unrooted, slash-separated, like "conf.d/a.json".
+type ioFileSystem struct {
+	fs.FS
+}
+
+func (f ioFileSystem) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(f.FS, name)
+}
+
+func (f ioFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(f.FS, name)
+}
+
+func (f ioFileSystem) WalkDir(root string, fn fs.WalkDirFunc) error {
+	return fs.WalkDir(f.FS, root, fn)
+}
+
+func (ioFileSystem) Join(elem ...string) string      { return path.Join(elem...) }
+func (ioFileSystem) FromSlash(pattern string) string { return pattern }
+func (ioFileSystem) ToSlash(name string) string      { return name }
+
+func (ioFileSystem) Resolve(base, name string) string {
+	if base == "" {
+		return path.Clean(name)
+	}
+	return path.Join(path.Dir(base), name)
+}
+
+func (ioFileSystem) Rel(base, target string) (string, error) {
+	if base == "." {
+		return target, nil
+	}
+	if target == base {
+		return ".", nil
+	}
+	return strings.TrimPrefix(target, base+"/"), nil
+}
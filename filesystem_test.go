@@ -0,0 +1,107 @@
+package jsons_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/qjebbs/go-jsons"
+)
+
+func TestMergeWithFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"base.json":              {Data: []byte(`{"log":{"level":"debug"},"list":[0]}`)},
+		"conf.d/10_b.json":       {Data: []byte(`{"list":[10]}`)},
+		"conf.d/2_a.json":        {Data: []byte(`{"list":[2]}`)},
+		"conf.d/host/1_c.jsonc":  {Data: []byte(`{"list":[1],} // comment`)},
+		"conf.d/host/readme.txt": {Data: []byte(`not a config`)},
+		"noext":                  {Data: []byte(`{"log":{"level":"info"}}`)},
+	}
+	m := jsons.NewMerger(jsons.WithFS(fsys))
+	testCases := []struct {
+		input interface{}
+		want  string
+	}{
+		{
+			input: "base.json",
+			want:  `{"log":{"level":"debug"},"list":[0]}`,
+		},
+		{
+			input: []string{"base.json", "conf.d", "noext"},
+			want:  `{"log":{"level":"info"},"list":[0,2,10]}`,
+		},
+		{
+			input: "conf.d/**/*.json*",
+			want:  `{"list":[2,10,1]}`,
+		},
+		{
+			input: "*",
+			want:  `{"log":{"level":"debug"},"list":[0]}`,
+		},
+		{
+			input: jsons.FSPath{Path: "conf.d/host"},
+			want:  `{"list":[1]}`,
+		},
+	}
+	for _, tc := range testCases {
+		got, err := m.Merge(tc.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tc.want {
+			t.Errorf("%v: want %s, got %s", tc.input, tc.want, got)
+		}
+	}
+	got, err := m.MergeAs(jsons.FormatJSON, "conf.d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"list":[2,10]}`; string(got) != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
+
+func TestMergeFSPath(t *testing.T) {
+	defaults := fstest.MapFS{
+		"defaults.json": {Data: []byte(`{"log":{"level":"debug"},"port":1080}`)},
+	}
+	overrides := fstest.MapFS{
+		"conf.d/a.json": {Data: []byte(`{"log":{"level":"info"}}`)},
+	}
+	got, err := jsons.Merge(
+		jsons.FSPath{FS: defaults, Path: "defaults.json"},
+		[]jsons.FSPath{{FS: overrides, Path: "conf.d"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"log":{"level":"info"},"port":1080}`; string(got) != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+	got, err = jsons.NewMerger().MergeAs(
+		jsons.FormatJSON,
+		jsons.FSPath{FS: defaults, Path: "defaults.json"},
+		[]jsons.FSPath{{FS: overrides, Path: "conf.d/a.json"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"log":{"level":"info"},"port":1080}`; string(got) != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
+
+func TestMergeFSNotExist(t *testing.T) {
+	m := jsons.NewMerger(jsons.WithFS(fstest.MapFS{}))
+	for _, input := range []interface{}{"a.json", "a", jsons.FSPath{Path: "a.json"}} {
+		_, err := m.Merge(input)
+		var me *jsons.MergeError
+		if !errors.As(err, &me) {
+			t.Fatalf("want *jsons.MergeError, got %T: %v", err, err)
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want fs.ErrNotExist, got %v", err)
+		}
+	}
+}
@@ -7,4 +7,10 @@ type Format string
 const (
 	FormatAuto Format = "auto"
 	FormatJSON Format = "json"
+	// FormatJSONC is JSON with comments and trailing commas,
+	// for files with the extension ".jsonc".
+	FormatJSONC Format = "jsonc"
+	// FormatJSONPatch is the JSON Patch (RFC 6902) format, whose documents
+	// are applied in order to the merged result of the previous inputs.
+	FormatJSONPatch Format = "jsonpatch"
 )
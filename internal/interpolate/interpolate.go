@@ -0,0 +1,243 @@
+// Package interpolate expands variable and JSON pointer references in
+// string values of a document.
+package interpolate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/qjebbs/go-jsons/internal/ordered"
+	"github.com/qjebbs/go-jsons/internal/pointer"
+)
+
+var (
+	// ErrUndefined is the error of referencing undefined variables or values
+	ErrUndefined = errors.New("undefined reference")
+	// ErrCycle is the error of references referring to themselves
+	ErrCycle = errors.New("reference cycle")
+)
+
+// Error is the error of expanding the string at Path.
+type Error struct {
+	Path []string
+	Err  error
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", pointer.Format(e.Path), e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// LookupFunc looks up the variable named name
+type LookupFunc func(name string) (value string, ok bool)
+
+// Expand expands references in string values of root in place:
+//
+//	${NAME}            the variable NAME
+//	${NAME:-default}   the variable NAME, or default if it's undefined or empty
+//	${/path/to/value}  the value at the JSON pointer of root
+//	$${                the literal "${"
+//
+// A string which is a single pointer reference is replaced by the
+// referenced value, keeping its type, while pointer references inside
+// longer strings must refer to strings, numbers, booleans or null.
+func Expand(root *ordered.Map, lookup LookupFunc) error {
+	e := &expander{
+		root:      root,
+		lookup:    lookup,
+		expanded:  make(map[string]interface{}),
+		resolving: make(map[string]bool),
+	}
+	_, err := e.walk(nil, root)
+	return err
+}
+
+type expander struct {
+	root   *ordered.Map
+	lookup LookupFunc
+	// expanded are the expanded strings by their pointers
+	expanded map[string]interface{}
+	// resolving are the pointers being resolved, to detect cycles
+	resolving map[string]bool
+}
+
+// walk expands the strings in v at path, and returns the expanded v
+func (e *expander) walk(path []string, v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return e.expandAt(path, v)
+	case *ordered.Map:
+		for _, k := range v.Keys {
+			expanded, err := e.walk(pointer.Append(path, k), v.Values[k])
+			if err != nil {
+				return nil, err
+			}
+			v.Values[k] = expanded
+		}
+	case []interface{}:
+		for i, elem := range v {
+			expanded, err := e.walk(pointer.Append(path, strconv.Itoa(i)), elem)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = expanded
+		}
+	}
+	return v, nil
+}
+
+// expandAt expands the string s at path, where each string
+// is expanded only once.
+func (e *expander) expandAt(path []string, s string) (interface{}, error) {
+	ptr := pointer.Format(path)
+	if v, ok := e.expanded[ptr]; ok {
+		return v, nil
+	}
+	if e.resolving[ptr] {
+		return nil, &Error{Path: path, Err: ErrCycle}
+	}
+	e.resolving[ptr] = true
+	defer delete(e.resolving, ptr)
+	v, err := e.expand(s)
+	if err != nil {
+		var ie *Error
+		if errors.As(err, &ie) {
+			return nil, err
+		}
+		return nil, &Error{Path: path, Err: err}
+	}
+	e.markExpanded(path, v)
+	return v, nil
+}
+
+// markExpanded marks the strings in the expanded value v at path
+// as expanded, so that they are never expanded again.
+func (e *expander) markExpanded(path []string, v interface{}) {
+	switch v := v.(type) {
+	case *ordered.Map:
+		for _, k := range v.Keys {
+			e.markExpanded(pointer.Append(path, k), v.Values[k])
+		}
+	case []interface{}:
+		for i, elem := range v {
+			e.markExpanded(pointer.Append(path, strconv.Itoa(i)), elem)
+		}
+	}
+	e.expanded[pointer.Format(path)] = v
+}
+
+// expand expands the references in s
+func (e *expander) expand(s string) (interface{}, error) {
+	if strings.HasPrefix(s, "${/") && strings.Index(s, "}") == len(s)-1 {
+		// a single pointer reference, keep the type
+		return e.resolvePointer(s[2 : len(s)-1])
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		if i > 0 && s[i-1] == '$' {
+			// escaped "$${"
+			b.WriteString(s[:i-1])
+			b.WriteString("${")
+			s = s[i+2:]
+			continue
+		}
+		b.WriteString(s[:i])
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated reference: %q", s[i:])
+		}
+		ref := s[i+2 : i+end]
+		s = s[i+end+1:]
+		v, err := e.resolve(ref)
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(v)
+	}
+}
+
+// resolve resolves the reference ref inside a string
+func (e *expander) resolve(ref string) (string, error) {
+	if ref == "" {
+		return "", errors.New("empty reference")
+	}
+	if ref[0] != '/' {
+		return e.resolveVariable(ref)
+	}
+	v, err := e.resolvePointer(ref)
+	if err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case *ordered.Map, []interface{}:
+		return "", fmt.Errorf("cannot interpolate %s of %s into string", typeName(v), ref)
+	default:
+		bs, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(bs), nil
+	}
+}
+
+// resolveVariable resolves the variable reference ref,
+// which is "NAME" or "NAME:-default".
+func (e *expander) resolveVariable(ref string) (string, error) {
+	name, def, hasDefault := strings.Cut(ref, ":-")
+	if v, ok := e.lookup(name); ok && (v != "" || !hasDefault) {
+		return v, nil
+	}
+	if hasDefault {
+		return def, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrUndefined, name)
+}
+
+// resolvePointer resolves the value at the JSON pointer ptr of root,
+// with the strings in it expanded.
+func (e *expander) resolvePointer(ptr string) (interface{}, error) {
+	path, err := pointer.Parse(ptr)
+	if err != nil {
+		return nil, err
+	}
+	v, err := ordered.Get(e.root, path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUndefined, ptr)
+	}
+	if s, ok := v.(string); ok {
+		return e.expandAt(path, s)
+	}
+	key := pointer.Format(path)
+	if e.resolving[key] {
+		return nil, &Error{Path: path, Err: ErrCycle}
+	}
+	e.resolving[key] = true
+	defer delete(e.resolving, key)
+	v, err = e.walk(path, v)
+	if err != nil {
+		return nil, err
+	}
+	return ordered.DeepCopy(v), nil
+}
+
+func typeName(v interface{}) string {
+	if _, ok := v.(*ordered.Map); ok {
+		return "object"
+	}
+	return "array"
+}
@@ -0,0 +1,104 @@
+package interpolate_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/qjebbs/go-jsons/internal/interpolate"
+	"github.com/qjebbs/go-jsons/internal/ordered"
+)
+
+var env = map[string]string{
+	"HOST":  "example.com",
+	"EMPTY": "",
+}
+
+func lookup(name string) (string, bool) {
+	v, ok := env[name]
+	return v, ok
+}
+
+func TestExpand(t *testing.T) {
+	testCases := []struct {
+		input string
+		want  string
+	}{
+		{
+			input: `{"a":"${HOST}:${PORT:-80}","b":"${EMPTY:-x}|${EMPTY}","c":"$${HOST}"}`,
+			want:  `{"a":"example.com:80","b":"x|","c":"${HOST}"}`,
+		},
+		{
+			input: `{"a":"${/b/port}","b":{"port":1080,"host":"${HOST}"},"c":"${/b/host}:${/b/port}"}`,
+			want:  `{"a":1080,"b":{"port":1080,"host":"example.com"},"c":"example.com:1080"}`,
+		},
+		{
+			input: `{"a":"${/b}","b":{"x":["${HOST}","$${lit}"]},"c":"${/a/x/1}"}`,
+			want:  `{"a":{"x":["example.com","${lit}"]},"b":{"x":["example.com","${lit}"]},"c":"${lit}"}`,
+		},
+		{
+			input: `{"a":"${/b}","b":"${/c}","c":true,"d":"${/c}${/e}","e":null}`,
+			want:  `{"a":true,"b":true,"c":true,"d":"truenull","e":null}`,
+		},
+		{
+			input: `{"a/b":"x","c":"${/a~1b}","d":[{"e":"${/c}"}]}`,
+			want:  `{"a/b":"x","c":"x","d":[{"e":"x"}]}`,
+		},
+	}
+	for _, tc := range testCases {
+		m := ordered.New()
+		if err := json.Unmarshal([]byte(tc.input), m); err != nil {
+			t.Fatal(err)
+		}
+		if err := interpolate.Expand(m, lookup); err != nil {
+			t.Errorf("%s: %v", tc.input, err)
+			continue
+		}
+		got, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := ordered.New()
+		if err := json.Unmarshal([]byte(tc.want), want); err != nil {
+			t.Fatal(err)
+		}
+		if !ordered.DeepEqual(want, m) {
+			t.Errorf("%s:\nwant %s\ngot  %s", tc.input, tc.want, got)
+		}
+	}
+}
+
+func TestExpandError(t *testing.T) {
+	testCases := []struct {
+		input string
+		path  string
+		err   error
+	}{
+		{input: `{"a":{"b":"${UNDEFINED}"}}`, path: "/a/b", err: interpolate.ErrUndefined},
+		{input: `{"a":"${/b}"}`, path: "/a", err: interpolate.ErrUndefined},
+		{input: `{"a":"${/b}","b":"${/a}"}`, path: "/a", err: interpolate.ErrCycle},
+		{input: `{"a":{"b":"${/a}"}}`, path: "/a/b", err: interpolate.ErrCycle},
+		{input: `{"a":"x${/b}","b":[1]}`, path: "/a"},
+		{input: `{"a":"${HOST"}`, path: "/a"},
+		{input: `{"a":"${}"}`, path: "/a"},
+		{input: `{"a":"${b}"}`, path: "/a", err: interpolate.ErrUndefined},
+	}
+	for _, tc := range testCases {
+		m := ordered.New()
+		if err := json.Unmarshal([]byte(tc.input), m); err != nil {
+			t.Fatal(err)
+		}
+		err := interpolate.Expand(m, lookup)
+		var ie *interpolate.Error
+		if !errors.As(err, &ie) {
+			t.Errorf("%s: want *interpolate.Error, got %v", tc.input, err)
+			continue
+		}
+		if got := err.Error(); len(got) < len(tc.path) || got[:len(tc.path)] != tc.path {
+			t.Errorf("%s: want error at %s, got %v", tc.input, tc.path, err)
+		}
+		if tc.err != nil && !errors.Is(err, tc.err) {
+			t.Errorf("%s: want %v, got %v", tc.input, tc.err, err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Package jsonc converts JSON with comments and trailing commas into standard JSON.
+package jsonc
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Error is the error of malformed comments.
+type Error struct {
+	Line, Column int
+	Msg          string
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at line %d, column %d", e.Msg, e.Line, e.Column)
+}
+
+// Position returns the 1-based line and column where the error occurred.
+func (e *Error) Position() (line, column int) {
+	return e.Line, e.Column
+}
+
+// Standardize converts b into standard JSON, by replacing comments
+// (`// ...` and `/* ... */`) and trailing commas with spaces.
+//
+// Line breaks and the offsets of other bytes are kept, so that the
+// positions reported by the JSON decoder still apply to b.
+// Other syntax errors are left to the JSON decoder.
+func Standardize(b []byte) ([]byte, error) {
+	out := make([]byte, len(b))
+	copy(out, b)
+	// the index of the pending comma, which is a trailing
+	// comma if followed by '}' or ']'
+	comma := -1
+	for i := 0; i < len(out); i++ {
+		switch c := out[i]; c {
+		case ' ', '\t', '\r', '\n':
+		case '"':
+			comma = -1
+			i = skipString(out, i)
+		case '/':
+			end, err := skipComment(out, i)
+			if err != nil {
+				return nil, err
+			}
+			blank(out[i:end])
+			i = end - 1
+		case ',':
+			comma = i
+		case '}', ']':
+			if comma >= 0 {
+				out[comma] = ' '
+			}
+			comma = -1
+		default:
+			comma = -1
+		}
+	}
+	return out, nil
+}
+
+// skipString returns the index of the closing quote of the string
+// starting at b[i], or the last index if the string is unterminated.
+func skipString(b []byte, i int) int {
+	for i++; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return len(b) - 1
+}
+
+// skipComment returns the end index of the comment starting at b[i].
+func skipComment(b []byte, i int) (int, error) {
+	if i+1 >= len(b) {
+		return 0, newError(b, i, "invalid character '/'")
+	}
+	switch b[i+1] {
+	case '/':
+		end := bytes.IndexByte(b[i:], '\n')
+		if end < 0 {
+			return len(b), nil
+		}
+		return i + end, nil
+	case '*':
+		end := bytes.Index(b[i+2:], []byte("*/"))
+		if end < 0 {
+			return 0, newError(b, i, "unterminated comment")
+		}
+		return i + 2 + end + 2, nil
+	default:
+		return 0, newError(b, i, "invalid character '/'")
+	}
+}
+
+// blank replaces the bytes with spaces, except the line breaks.
+func blank(b []byte) {
+	for i, c := range b {
+		if c != '\n' && c != '\r' {
+			b[i] = ' '
+		}
+	}
+}
+
+func newError(b []byte, i int, msg string) *Error {
+	line := bytes.Count(b[:i], []byte("\n")) + 1
+	column := i - bytes.LastIndexByte(b[:i], '\n')
+	return &Error{Line: line, Column: column, Msg: msg}
+}
@@ -0,0 +1,76 @@
+package jsonc_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/qjebbs/go-jsons/internal/jsonc"
+)
+
+func TestStandardize(t *testing.T) {
+	testCases := []struct {
+		input string
+		want  string
+	}{
+		{
+			input: `{"a":1}`,
+			want:  `{"a":1}`,
+		},
+		{
+			input: "{\n// comment\n\"a\":1 /* comment */}",
+			want:  "{\n          \n\"a\":1              }",
+		},
+		{
+			input: `{"a":[1,2,],}`,
+			want:  `{"a":[1,2 ] }`,
+		},
+		{
+			input: "{\"a\":1, // comment\n}",
+			want:  "{\"a\":1            \n}",
+		},
+		{
+			input: `{"a":"//,}","b":"\"/*"}`,
+			want:  `{"a":"//,}","b":"\"/*"}`,
+		},
+		{
+			input: "{\"a\":1}\n// comment",
+			want:  "{\"a\":1}\n          ",
+		},
+		{
+			input: `[1,,]`,
+			want:  `[1, ]`,
+		},
+	}
+	for _, tc := range testCases {
+		got, err := jsonc.Standardize([]byte(tc.input))
+		if err != nil {
+			t.Errorf("%q: %v", tc.input, err)
+			continue
+		}
+		if string(got) != tc.want {
+			t.Errorf("%q: want %q, got %q", tc.input, tc.want, got)
+		}
+	}
+}
+
+func TestStandardizeError(t *testing.T) {
+	testCases := []struct {
+		input        string
+		line, column int
+	}{
+		{input: "{\n  /* comment", line: 2, column: 3},
+		{input: `{"a":1/}`, line: 1, column: 7},
+		{input: `{"a":1}/`, line: 1, column: 8},
+	}
+	for _, tc := range testCases {
+		_, err := jsonc.Standardize([]byte(tc.input))
+		var e *jsonc.Error
+		if !errors.As(err, &e) {
+			t.Errorf("%q: want *jsonc.Error, got %v", tc.input, err)
+			continue
+		}
+		if line, column := e.Position(); line != tc.line || column != tc.column {
+			t.Errorf("%q: want %d:%d, got %d:%d", tc.input, tc.line, tc.column, line, column)
+		}
+	}
+}
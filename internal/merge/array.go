@@ -0,0 +1,146 @@
+package merge
+
+import (
+	"strconv"
+
+	"github.com/qjebbs/go-jsons/internal/ordered"
+	"github.com/qjebbs/go-jsons/internal/pointer"
+)
+
+// ArrayStrategy is the strategy to merge arrays
+type ArrayStrategy string
+
+// Array merge strategies
+const (
+	// ArrayAppend appends incoming elements to the existing ones
+	ArrayAppend ArrayStrategy = "append"
+	// ArrayPrepend inserts incoming elements before the existing ones
+	ArrayPrepend ArrayStrategy = "prepend"
+	// ArrayReplace replaces the existing array with the incoming one
+	ArrayReplace ArrayStrategy = "replace"
+	// ArrayMergeByIndex merges elements at the same index
+	ArrayMergeByIndex ArrayStrategy = "merge-by-index"
+	// ArrayUnion appends incoming elements which are not deeply equal to any existing one
+	ArrayUnion ArrayStrategy = "union"
+)
+
+// Valid tells if the strategy is a known one
+func (s ArrayStrategy) Valid() bool {
+	switch s {
+	case ArrayAppend, ArrayPrepend, ArrayReplace, ArrayMergeByIndex, ArrayUnion:
+		return true
+	}
+	return false
+}
+
+// ArrayRule selects the strategy of arrays whose paths match the pattern
+type ArrayRule struct {
+	Pattern  *pointer.Pattern
+	Strategy ArrayStrategy
+}
+
+// arrayStrategy returns the strategy for the array at path,
+// the last matched rule wins.
+func (o *Options) arrayStrategy(path []string) ArrayStrategy {
+	for i := len(o.ArrayRules) - 1; i >= 0; i-- {
+		if o.ArrayRules[i].Pattern.Match(path) {
+			return o.ArrayRules[i].Strategy
+		}
+	}
+	if o.MergePatch {
+		return ArrayReplace
+	}
+	return ArrayAppend
+}
+
+func (o *Options) mergeSlices(path []string, target, source []interface{}, to, so *origin) ([]interface{}, *origin, error) {
+	return o.mergeSlicesBy(o.arrayStrategy(path), path, target, source, to, so)
+}
+
+func (o *Options) mergeSlicesBy(strategy ArrayStrategy, path []string, target, source []interface{}, to, so *origin) ([]interface{}, *origin, error) {
+	if strategy != ArrayMergeByIndex {
+		var err error
+		source, err = o.resolveSlice(path, source)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	// origins of elements, which are tracked only if so is not nil
+	var tElems, sElems, elems []*origin
+	if so != nil {
+		if to == nil {
+			to = &origin{}
+		}
+		tElems, sElems = to.elems(len(target)), so.elems(len(source))
+	}
+	switch strategy {
+	case ArrayAppend:
+		target = append(target, source...)
+		elems = append(tElems, sElems...)
+	case ArrayPrepend:
+		s := make([]interface{}, 0, len(target)+len(source))
+		s = append(s, source...)
+		target = append(s, target...)
+		elems = append(sElems, tElems...)
+	case ArrayReplace:
+		target, elems = source, sElems
+	case ArrayMergeByIndex:
+		elems = tElems
+		for i, value := range source {
+			var (
+				current interface{}
+				co, eo  *origin
+			)
+			if i < len(target) {
+				current = target[i]
+			}
+			if so != nil {
+				eo = sElems[i]
+				if i < len(tElems) {
+					co = tElems[i]
+				}
+			}
+			merged, mo, err := o.mergeOrderedField(pointer.Append(path, strconv.Itoa(i)), current, value, co, eo)
+			if err != nil {
+				return nil, nil, err
+			}
+			if i < len(target) {
+				target[i] = merged
+				if so != nil {
+					elems[i] = mo
+				}
+			} else {
+				target = append(target, merged)
+				if so != nil {
+					elems = append(elems, mo)
+				}
+			}
+		}
+	case ArrayUnion:
+		elems = tElems
+		for i, value := range source {
+			if containsValue(target, value) {
+				continue
+			}
+			target = append(target, value)
+			if so != nil {
+				elems = append(elems, sElems[i])
+			}
+		}
+	default:
+		return nil, nil, errorAt(path, "unknown array strategy: %s", strategy)
+	}
+	if so == nil {
+		return target, nil, nil
+	}
+	return target, &origin{Source: so.Source, Elems: elems}, nil
+}
+
+func containsValue(s []interface{}, v interface{}) bool {
+	for _, e := range s {
+		if ordered.DeepEqual(e, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package merge_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/qjebbs/go-jsons/internal/merge"
+	"github.com/qjebbs/go-jsons/internal/ordered"
+	"github.com/qjebbs/go-jsons/internal/pointer"
+)
+
+func TestMergeArrayStrategies(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name     string
+		path     string
+		strategy merge.ArrayStrategy
+		values   []string
+		want     string
+	}{
+		{
+			name:     "append",
+			path:     "/a",
+			strategy: merge.ArrayAppend,
+			values:   []string{`{"a": [1, 2]}`, `{"a": [3]}`},
+			want:     `{"a": [1, 2, 3]}`,
+		},
+		{
+			name:     "prepend",
+			path:     "/a",
+			strategy: merge.ArrayPrepend,
+			values:   []string{`{"a": [1, 2]}`, `{"a": [3]}`},
+			want:     `{"a": [3, 1, 2]}`,
+		},
+		{
+			name:     "replace",
+			path:     "/a",
+			strategy: merge.ArrayReplace,
+			values:   []string{`{"a": [1, 2]}`, `{"a": [3]}`},
+			want:     `{"a": [3]}`,
+		},
+		{
+			name:     "merge_by_index",
+			path:     "/a",
+			strategy: merge.ArrayMergeByIndex,
+			values:   []string{`{"a": [{"b": 1}, 2]}`, `{"a": [{"c": 1}, null, 3]}`},
+			want:     `{"a": [{"b": 1, "c": 1}, 2, 3]}`,
+		},
+		{
+			name:     "union",
+			path:     "/a",
+			strategy: merge.ArrayUnion,
+			values:   []string{`{"a": [1, {"b": 1, "c": 2}]}`, `{"a": [{"c": 2, "b": 1}, 1, 3, 3]}`},
+			want:     `{"a": [1, {"b": 1, "c": 2}, 3]}`,
+		},
+		{
+			name:     "glob",
+			path:     "/a/*/b",
+			strategy: merge.ArrayReplace,
+			values:   []string{`{"a": {"x": {"b": [1]}, "y": {"c": [1]}}}`, `{"a": {"x": {"b": [2]}, "y": {"c": [2]}}}`},
+			want:     `{"a": {"x": {"b": [2]}, "y": {"c": [1, 2]}}}`,
+		},
+		{
+			name:     "unmatched",
+			path:     "/b",
+			strategy: merge.ArrayReplace,
+			values:   []string{`{"a": [1]}`, `{"a": [2]}`},
+			want:     `{"a": [1, 2]}`,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			pattern, err := pointer.ParsePattern(tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := &merge.Options{
+				ArrayRules: []merge.ArrayRule{{Pattern: pattern, Strategy: tc.strategy}},
+			}
+			want := ordered.FromMap(convertToMap(t, tc.want)).Sort()
+			got := ordered.New()
+			if err := opts.OrderedMaps(got, convertToOrderedMaps(t, tc.values)); err != nil {
+				t.Fatal(err)
+			}
+			got.Sort()
+			if !reflect.DeepEqual(want, got) {
+				t.Errorf("want:\n%v\n\ngot:\n%v", want, got)
+			}
+		})
+	}
+}
+
+func TestMergeArrayLastRuleWins(t *testing.T) {
+	t.Parallel()
+	all, _ := pointer.ParsePattern("/**")
+	a, _ := pointer.ParsePattern("/a")
+	opts := &merge.Options{
+		ArrayRules: []merge.ArrayRule{
+			{Pattern: all, Strategy: merge.ArrayReplace},
+			{Pattern: a, Strategy: merge.ArrayAppend},
+		},
+	}
+	got := ordered.New()
+	values := []string{`{"a": [1], "b": [1]}`, `{"a": [2], "b": [2]}`}
+	if err := opts.OrderedMaps(got, convertToOrderedMaps(t, values)); err != nil {
+		t.Fatal(err)
+	}
+	want := ordered.FromMap(convertToMap(t, `{"a": [1, 2], "b": [2]}`)).Sort()
+	got.Sort()
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want:\n%v\n\ngot:\n%v", want, got)
+	}
+}
+
+func TestMergeArrayUnknownStrategy(t *testing.T) {
+	t.Parallel()
+	a, _ := pointer.ParsePattern("/a")
+	opts := &merge.Options{
+		ArrayRules: []merge.ArrayRule{{Pattern: a, Strategy: "unknown"}},
+	}
+	values := []string{`{"a": [1]}`, `{"a": [2]}`}
+	if err := opts.OrderedMaps(ordered.New(), convertToOrderedMaps(t, values)); err == nil {
+		t.Error("want error, got nil")
+	}
+}
@@ -0,0 +1,128 @@
+package merge
+
+import (
+	"strconv"
+
+	"github.com/qjebbs/go-jsons/internal/ordered"
+	"github.com/qjebbs/go-jsons/internal/pointer"
+)
+
+// directive names, which are prefixed with Options.DirectivePrefix in documents
+const (
+	directiveReplace = "replace"
+	directiveDelete  = "delete"
+	directiveAppend  = "append"
+	directivePrepend = "prepend"
+)
+
+var directiveNames = []string{directiveReplace, directiveDelete, directiveAppend, directivePrepend}
+
+// isDirective tells if key is a directive key
+func (o *Options) isDirective(key string) bool {
+	if o.DirectivePrefix == "" || len(key) <= len(o.DirectivePrefix) || key[:len(o.DirectivePrefix)] != o.DirectivePrefix {
+		return false
+	}
+	name := key[len(o.DirectivePrefix):]
+	for _, n := range directiveNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// directive returns the value of directive name in m
+func (o *Options) directive(m *ordered.Map, name string) (interface{}, bool) {
+	if o.DirectivePrefix == "" {
+		return nil, false
+	}
+	v, ok := m.Values[o.DirectivePrefix+name]
+	return v, ok
+}
+
+// directiveTrue tells if the directive name of m is set to true
+func (o *Options) directiveTrue(m *ordered.Map, name string) bool {
+	v, _ := o.directive(m, name)
+	b, _ := v.(bool)
+	return b
+}
+
+// mergeDirectives merges source into target if source carries one of the
+// $replace, $append and $prepend directives, and reports whether it's handled.
+func (o *Options) mergeDirectives(path []string, target interface{}, source *ordered.Map, to *origin) (interface{}, *origin, bool, error) {
+	if v, ok := o.directive(source, directiveReplace); ok {
+		if b, ok := v.(bool); ok {
+			if !b {
+				return nil, nil, false, nil
+			}
+			r, err := o.resolve(path, source)
+			return r, o.sourceOrigin(source, o.DirectivePrefix+directiveReplace, r), true, err
+		}
+		r, err := o.resolve(path, v)
+		return r, o.sourceOrigin(source, o.DirectivePrefix+directiveReplace, v), true, err
+	}
+	for _, name := range []string{directiveAppend, directivePrepend} {
+		v, ok := o.directive(source, name)
+		if !ok {
+			continue
+		}
+		for _, key := range source.Keys {
+			if !o.isDirective(key) {
+				return nil, nil, true, errorAt(path, "'%s%s' cannot be used with other fields", o.DirectivePrefix, name)
+			}
+		}
+		elements, ok := v.([]interface{})
+		if !ok {
+			return nil, nil, true, errorAt(path, "'%s%s' expects an array, got %s", o.DirectivePrefix, name, TypeName(v))
+		}
+		so := o.sourceOrigin(source, o.DirectivePrefix+name, elements)
+		tslice, ok := target.([]interface{})
+		if !ok {
+			if target != nil && !o.TypeOverride {
+				return nil, nil, true, typeMismatch(path, target, elements)
+			}
+			r, err := o.resolveSlice(path, elements)
+			return r, so, true, err
+		}
+		strategy := ArrayAppend
+		if name == directivePrepend {
+			strategy = ArrayPrepend
+		}
+		r, mo, err := o.mergeSlicesBy(strategy, path, tslice, elements, to, so)
+		return r, mo, true, err
+	}
+	return nil, nil, false, nil
+}
+
+// resolve resolves directives of v, which is to be written to an empty place.
+// New maps are created for v if tracking or directives enabled.
+func (o *Options) resolve(path []string, v interface{}) (interface{}, error) {
+	if o.DirectivePrefix == "" && o.Tracker == nil {
+		return v, nil
+	}
+	switch v := v.(type) {
+	case *ordered.Map:
+		t := ordered.New()
+		err := o.mergeOrderedMap(path, t, v)
+		return t, err
+	case []interface{}:
+		return o.resolveSlice(path, v)
+	}
+	return v, nil
+}
+
+// resolveSlice resolves directives of elements in s.
+func (o *Options) resolveSlice(path []string, s []interface{}) ([]interface{}, error) {
+	if o.DirectivePrefix == "" && o.Tracker == nil {
+		return s, nil
+	}
+	r := make([]interface{}, len(s))
+	for i, e := range s {
+		v, err := o.resolve(pointer.Append(path, strconv.Itoa(i)), e)
+		if err != nil {
+			return nil, err
+		}
+		r[i] = v
+	}
+	return r, nil
+}
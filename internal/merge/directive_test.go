@@ -0,0 +1,143 @@
+package merge_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/qjebbs/go-jsons/internal/merge"
+	"github.com/qjebbs/go-jsons/internal/ordered"
+)
+
+func TestMergeDirectives(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name    string
+		values  []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "replace",
+			values: []string{
+				`{"a": {"b": 1, "c": 1}}`,
+				`{"a": {"$replace": true, "b": 2}}`,
+			},
+			want: `{"a": {"b": 2}}`,
+		},
+		{
+			name: "replace_false",
+			values: []string{
+				`{"a": {"b": 1, "c": 1}}`,
+				`{"a": {"$replace": false, "b": 2}}`,
+			},
+			want: `{"a": {"b": 2, "c": 1}}`,
+		},
+		{
+			name: "replace_value",
+			values: []string{
+				`{"a": [1, 2]}`,
+				`{"a": {"$replace": [3]}}`,
+			},
+			want: `{"a": [3]}`,
+		},
+		{
+			name: "replace_root",
+			values: []string{
+				`{"a": 1}`,
+				`{"$replace": true, "b": 1}`,
+			},
+			want: `{"b": 1}`,
+		},
+		{
+			name: "delete",
+			values: []string{
+				`{"a": 1, "b": {"c": 1}}`,
+				`{"a": {"$delete": true}, "b": {"c": {"$delete": true}}}`,
+			},
+			want: `{"b": {}}`,
+		},
+		{
+			name: "append_prepend",
+			values: []string{
+				`{"a": [1], "b": [1]}`,
+				`{"a": {"$append": [2]}, "b": {"$prepend": [2]}}`,
+			},
+			want: `{"a": [1, 2], "b": [2, 1]}`,
+		},
+		{
+			name: "no_target",
+			values: []string{
+				`{"a": {"$append": [1]}, "b": {"$delete": true}, "c": {"$replace": true, "d": {"$prepend": [{"e": {"$delete": true}, "f": 1}]}}}`,
+			},
+			want: `{"a": [1], "c": {"d": [{"f": 1}]}}`,
+		},
+		{
+			name: "appended_elements",
+			values: []string{
+				`{"a": [1]}`,
+				`{"a": [{"b": {"$append": [1]}}]}`,
+			},
+			want: `{"a": [1, {"b": [1]}]}`,
+		},
+		{
+			name: "unknown_kept",
+			values: []string{
+				`{"$schema": "a", "a": {"$ref": "b"}}`,
+			},
+			want: `{"$schema": "a", "a": {"$ref": "b"}}`,
+		},
+		{
+			name: "append_with_fields",
+			values: []string{
+				`{"a": {"$append": [1], "b": 1}}`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "append_not_array",
+			values: []string{
+				`{"a": {"$append": 1}}`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "append_type_mismatch",
+			values: []string{
+				`{"a": 1}`,
+				`{"a": {"$append": [1]}}`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "nested_error",
+			values: []string{
+				`{"a": [1]}`,
+				`{"a": [{"b": {"$append": 1}}]}`,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			opts := &merge.Options{DirectivePrefix: "$"}
+			got := ordered.New()
+			err := opts.OrderedMaps(got, convertToOrderedMaps(t, tc.values))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("want err got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := ordered.FromMap(convertToMap(t, tc.want)).Sort()
+			got.Sort()
+			if !reflect.DeepEqual(want, got) {
+				t.Errorf("want:\n%v\n\ngot:\n%v", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package merge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/qjebbs/go-jsons/internal/ordered"
+	"github.com/qjebbs/go-jsons/internal/pointer"
+)
+
+// ErrTypeMismatch is the error of merging values of different types
+var ErrTypeMismatch = errors.New("type mismatch")
+
+// Error is the error occurred when merging the value at Path
+type Error struct {
+	// Path is the path of the value
+	Path []string
+	// Expected and Incoming are the types of the existing
+	// and incoming values, when types mismatch.
+	Expected, Incoming string
+	// Source is the source of the incoming value, if it's tracked
+	Source string
+	Err    error
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	msg := e.Err.Error()
+	if e.Expected != "" {
+		msg = fmt.Sprintf("%s, expect %s, incoming %s", e.Err, e.Expected, e.Incoming)
+	}
+	if len(e.Path) == 0 {
+		return msg
+	}
+	return fmt.Sprintf("%s: %s", pointer.Format(e.Path), msg)
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func typeMismatch(path []string, target, source interface{}) *Error {
+	return &Error{
+		Path:     path,
+		Expected: TypeName(target),
+		Incoming: TypeName(source),
+		Err:      ErrTypeMismatch,
+	}
+}
+
+func errorAt(path []string, format string, args ...interface{}) *Error {
+	return &Error{
+		Path: path,
+		Err:  fmt.Errorf(format, args...),
+	}
+}
+
+// TypeName returns the JSON type name of v, or the Go type name
+// if v is not of the types decoded from JSON.
+func TypeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case *ordered.Map:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64, json.Number:
+		return "number"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// sameType tells if a and b are of the same JSON type, where numbers
+// of float64 and json.Number are of the same type.
+func sameType(a, b interface{}) bool {
+	return TypeName(a) == TypeName(b)
+}
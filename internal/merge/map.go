@@ -6,7 +6,6 @@ package merge
 
 import (
 	"fmt"
-	"reflect"
 )
 
 // Maps merges source maps into target
@@ -39,7 +38,7 @@ func mergeField(typeOverride bool, target interface{}, source interface{}) (inte
 	if target == nil {
 		return source, nil
 	}
-	if reflect.TypeOf(source) != reflect.TypeOf(target) {
+	if !sameType(source, target) {
 		if !typeOverride {
 			return nil, fmt.Errorf("type mismatch, expect %T, incoming %T", target, source)
 		}
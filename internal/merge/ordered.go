@@ -1,16 +1,50 @@
 package merge
 
 import (
-	"fmt"
-	"reflect"
+	"context"
+	"errors"
 
 	"github.com/qjebbs/go-jsons/internal/ordered"
+	"github.com/qjebbs/go-jsons/internal/pointer"
 )
 
+// Options is the options for merging ordered maps
+type Options struct {
+	// TypeOverride allows a value to be overridden by a value of another type
+	TypeOverride bool
+	// MergePatch makes the merging follow RFC 7396 JSON Merge Patch:
+	// null deletes the field, arrays are replaced, objects are merged recursively.
+	MergePatch bool
+	// ArrayRules selects array merge strategies by path
+	ArrayRules []ArrayRule
+	// DirectivePrefix enables in-document merge directives if not empty,
+	// e.g.: "$" enables "$replace", "$delete", "$append" and "$prepend".
+	DirectivePrefix string
+	// Tracker tracks the sources of merged values if not nil
+	Tracker *Tracker
+	// Context stops the merging when it's done, if not nil
+	Context context.Context
+	// Source is the name of the source being merged, for the tracker
+	Source string
+	// OnConflict is called if not nil, before a non-container value
+	// written by a source is overwritten by a different value of another
+	// source, where prev and cur are the sources, which requires Tracker.
+	// Merging stops with the error it returns.
+	OnConflict func(path []string, target, source interface{}, prev, cur string) error
+}
+
 // OrderedMaps merges source ordered maps into target
 func OrderedMaps(target *ordered.Map, sources []*ordered.Map, typeOverride bool) (err error) {
+	return (&Options{TypeOverride: typeOverride}).OrderedMaps(target, sources)
+}
+
+// OrderedMaps merges source ordered maps into target according to the options
+func (o *Options) OrderedMaps(target *ordered.Map, sources []*ordered.Map) (err error) {
 	for _, source := range sources {
-		err = mergeOrderedMap(target, source, typeOverride)
+		if o.directiveTrue(source, directiveReplace) || o.directiveTrue(source, directiveDelete) {
+			*target = *ordered.New()
+		}
+		err = o.mergeOrderedMap(nil, target, source)
 		if err != nil {
 			return err
 		}
@@ -18,43 +52,158 @@ func OrderedMaps(target *ordered.Map, sources []*ordered.Map, typeOverride bool)
 	return nil
 }
 
-func mergeOrderedMap(target *ordered.Map, source *ordered.Map, typeOverride bool) (err error) {
-	for _, sk := range source.Keys {
-		if _, exists := target.Values[sk]; !exists {
-			target.Keys = append(target.Keys, sk)
+// OrderedMapAt merges source into target, which is located at path
+// of the whole document, e.g.: an element of an array.
+func (o *Options) OrderedMapAt(path []string, target *ordered.Map, source *ordered.Map) error {
+	return o.mergeOrderedMap(path, target, source)
+}
+
+func (o *Options) mergeOrderedMap(path []string, target *ordered.Map, source *ordered.Map) (err error) {
+	if o.Context != nil {
+		if err := o.Context.Err(); err != nil {
+			return &Error{Path: path, Err: err}
 		}
 	}
-	for key, value := range source.Values {
-		target.Values[key], err = mergeOrderedField(target.Values[key], value, typeOverride)
+	for _, key := range source.Keys {
+		if o.isDirective(key) {
+			continue
+		}
+		value := source.Values[key]
+		if o.MergePatch && value == nil {
+			target.Remove(key)
+			o.Tracker.remove(target, key)
+			continue
+		}
+		if m, ok := value.(*ordered.Map); ok && o.directiveTrue(m, directiveDelete) {
+			target.Remove(key)
+			o.Tracker.remove(target, key)
+			continue
+		}
+		so := o.sourceOrigin(source, key, value)
+		merged, origin, err := o.mergeOrderedField(
+			pointer.Append(path, key),
+			target.Values[key], value,
+			o.Tracker.get(target, key), so,
+		)
 		if err != nil {
-			return fmt.Errorf("field '%s': %s", key, err)
+			var e *Error
+			if so != nil && errors.As(err, &e) && e.Source == "" {
+				e.Source = so.Source
+			}
+			return err
 		}
+		target.Set(key, merged)
+		o.Tracker.set(target, key, origin)
 	}
 	return nil
 }
 
-func mergeOrderedField(target interface{}, source interface{}, typeOverride bool) (interface{}, error) {
+// mergeOrderedField merges source into target, and returns the merged value and its origin,
+// where to and so are the origins of target and source, which are nil if not tracked.
+func (o *Options) mergeOrderedField(path []string, target, source interface{}, to, so *origin) (interface{}, *origin, error) {
+	if smap, ok := source.(*ordered.Map); ok {
+		merged, mo, handled, err := o.mergeDirectives(path, target, smap, to)
+		if handled {
+			return merged, mo, err
+		}
+	}
+	if o.MergePatch {
+		return o.mergePatchField(path, target, source, to, so)
+	}
 	if source == nil {
-		return target, nil
+		return target, to, nil
 	}
 	if target == nil {
-		return source, nil
+		v, err := o.resolve(path, source)
+		return v, so, err
 	}
-	if reflect.TypeOf(source) != reflect.TypeOf(target) {
-		if !typeOverride {
-			return nil, fmt.Errorf("type mismatch, expect %T, incoming %T", target, source)
+	if !sameType(source, target) {
+		if !o.TypeOverride {
+			return nil, nil, typeMismatch(path, target, source)
 		}
-		return source, nil
+		if err := o.checkConflict(path, target, source, to, so); err != nil {
+			return nil, nil, err
+		}
+		v, err := o.resolve(path, source)
+		return v, so, err
 	}
 	if slice, ok := source.([]interface{}); ok {
 		tslice, _ := target.([]interface{})
-		tslice = append(tslice, slice...)
-		return tslice, nil
+		return o.mergeSlices(path, tslice, slice, to, so)
 	}
 	if smap, ok := source.(*ordered.Map); ok {
 		tmap, _ := target.(*ordered.Map)
-		err := mergeOrderedMap(tmap, smap, typeOverride)
-		return tmap, err
+		err := o.mergeOrderedMap(path, tmap, smap)
+		return tmap, so, err
+	}
+	if err := o.checkConflict(path, target, source, to, so); err != nil {
+		return nil, nil, err
+	}
+	return source, so, nil
+}
+
+// checkConflict calls OnConflict if the non-container target
+// is going to be overwritten by a different source value.
+func (o *Options) checkConflict(path []string, target, source interface{}, to, so *origin) error {
+	if o.OnConflict == nil || target == nil || source == nil {
+		return nil
+	}
+	switch target.(type) {
+	case *ordered.Map, []interface{}:
+		return nil
+	}
+	if ordered.DeepEqual(target, source) {
+		return nil
+	}
+	var prev, cur string
+	if to != nil {
+		prev = to.Source
+	}
+	if so != nil {
+		cur = so.Source
+	}
+	if prev == cur {
+		// overwritten by the same source
+		return nil
+	}
+	if err := o.OnConflict(path, target, source, prev, cur); err != nil {
+		return &Error{Path: path, Err: err}
+	}
+	return nil
+}
+
+// mergePatchField merges source into target as described in RFC 7396
+func (o *Options) mergePatchField(path []string, target, source interface{}, to, so *origin) (interface{}, *origin, error) {
+	switch s := source.(type) {
+	case *ordered.Map:
+		tmap, ok := target.(*ordered.Map)
+		if !ok {
+			// members of an object written to an empty place are
+			// merged into a new one, so that nulls are removed
+			tmap = ordered.New()
+		}
+		err := o.mergeOrderedMap(path, tmap, s)
+		return tmap, so, err
+	case []interface{}:
+		if tslice, ok := target.([]interface{}); ok {
+			return o.mergeSlices(path, tslice, s, to, so)
+		}
+	}
+	// arrays and simple values are replaced
+	if err := o.checkConflict(path, target, source, to, so); err != nil {
+		return nil, nil, err
+	}
+	v, err := o.resolve(path, source)
+	return v, so, err
+}
+
+// sourceOrigin returns the origin of source[key], which is v
+func (o *Options) sourceOrigin(source *ordered.Map, key string, v interface{}) *origin {
+	if o.Tracker == nil {
+		return nil
+	}
+	if so := o.Tracker.get(source, key); so != nil {
+		return so
 	}
-	return source, nil
+	return newOrigin(v, o.Source)
 }
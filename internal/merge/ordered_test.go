@@ -49,3 +49,69 @@ func convertToOrderedMaps(t *testing.T, values []string) []*ordered.Map {
 	}
 	return s
 }
+
+func TestMergePatch(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name   string
+		values []string
+		want   string
+	}{
+		{
+			name: "null_deletes",
+			values: []string{
+				`{"a": 1, "b": 2}`,
+				`{"a": null}`,
+			},
+			want: `{"b": 2}`,
+		},
+		{
+			name: "array_replaced",
+			values: []string{
+				`{"a": [1, 2]}`,
+				`{"a": [3]}`,
+			},
+			want: `{"a": [3]}`,
+		},
+		{
+			name: "object_recursive",
+			values: []string{
+				`{"a": {"b": 1, "c": 2}}`,
+				`{"a": {"b": null, "d": 3}}`,
+			},
+			want: `{"a": {"c": 2, "d": 3}}`,
+		},
+		{
+			name: "type_replaced",
+			values: []string{
+				`{"a": {"b": 1}, "c": 1}`,
+				`{"a": [1], "c": {"d": 1, "e": null}}`,
+			},
+			want: `{"a": [1], "c": {"d": 1}}`,
+		},
+		{
+			name: "no_null_added",
+			values: []string{
+				`{"a": null, "b": {"c": null}}`,
+			},
+			want: `{"b": {}}`,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			want := ordered.FromMap(convertToMap(t, tc.want)).Sort()
+			items := convertToOrderedMaps(t, tc.values)
+			got := ordered.New()
+			opts := &merge.Options{MergePatch: true}
+			if err := opts.OrderedMaps(got, items); err != nil {
+				t.Fatal(err)
+			}
+			got.Sort()
+			if !reflect.DeepEqual(want, got) {
+				t.Errorf("want:\n%v\n\ngot:\n%v", want, got)
+			}
+		})
+	}
+}
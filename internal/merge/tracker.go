@@ -0,0 +1,285 @@
+package merge
+
+import (
+	"strconv"
+
+	"github.com/qjebbs/go-jsons/internal/ordered"
+	"github.com/qjebbs/go-jsons/internal/pointer"
+)
+
+// origin is the origin of a value
+type origin struct {
+	// Source is the source who last wrote the value
+	Source string
+	// Elems are origins of the elements, if the value is an array
+	Elems []*origin
+}
+
+// newOrigin creates the origin of v, who comes from source entirely
+func newOrigin(v interface{}, source string) *origin {
+	o := &origin{Source: source}
+	if s, ok := v.([]interface{}); ok {
+		o.Elems = make([]*origin, len(s))
+		for i, e := range s {
+			o.Elems[i] = newOrigin(e, source)
+		}
+	}
+	return o
+}
+
+// elems returns the origins of n elements,
+// where elements not tracked are considered from o.Source
+func (o *origin) elems(n int) []*origin {
+	r := make([]*origin, n)
+	copy(r, o.Elems)
+	for i := len(o.Elems); i < n; i++ {
+		r[i] = &origin{Source: o.Source}
+	}
+	return r
+}
+
+// Tracker tracks the sources of values written during merging.
+//
+// Values are tracked by the maps holding them, so that they can be
+// followed even if the arrays holding the maps are sorted or merged.
+type Tracker struct {
+	fields map[*ordered.Map]map[string]*origin
+	// removed is the origin of the last removed value
+	removed *origin
+}
+
+// NewTracker creates a new Tracker
+func NewTracker() *Tracker {
+	return &Tracker{
+		fields: make(map[*ordered.Map]map[string]*origin),
+	}
+}
+
+func (t *Tracker) get(m *ordered.Map, key string) *origin {
+	if t == nil {
+		return nil
+	}
+	return t.fields[m][key]
+}
+
+func (t *Tracker) set(m *ordered.Map, key string, o *origin) {
+	if t == nil || o == nil {
+		return
+	}
+	fields, ok := t.fields[m]
+	if !ok {
+		fields = make(map[string]*origin)
+		t.fields[m] = fields
+	}
+	fields[key] = o
+}
+
+func (t *Tracker) remove(m *ordered.Map, key string) {
+	if t == nil {
+		return
+	}
+	delete(t.fields[m], key)
+}
+
+// Source returns the source who last wrote the field key of m
+func (t *Tracker) Source(m *ordered.Map, key string) (string, bool) {
+	o := t.get(m, key)
+	if o == nil {
+		return "", false
+	}
+	return o.Source, true
+}
+
+// Reorder reorders the origins of elements of the array m[key],
+// where order[i] is the original index of the i-th element.
+// Nothing changes if order is nil.
+func (t *Tracker) Reorder(m *ordered.Map, key string, order []int) {
+	o := t.get(m, key)
+	if o == nil || order == nil {
+		return
+	}
+	elems := o.elems(len(order))
+	n := len(o.Elems)
+	for i, j := range order {
+		if j < n {
+			elems[i] = o.Elems[j]
+		}
+	}
+	o.Elems = elems
+}
+
+// Record records that v written to m[key] comes from source entirely.
+func (t *Tracker) Record(m *ordered.Map, key string, v interface{}, source string) {
+	if t == nil {
+		return
+	}
+	t.set(m, key, newOrigin(v, source))
+	t.recordChildren(v, source)
+}
+
+func (t *Tracker) recordChildren(v interface{}, source string) {
+	switch v := v.(type) {
+	case *ordered.Map:
+		for _, k := range v.Keys {
+			t.Record(v, k, v.Values[k], source)
+		}
+	case []interface{}:
+		for _, e := range v {
+			t.recordChildren(e, source)
+		}
+	}
+}
+
+// Removed should be called after the value at path of root is removed,
+// the origin of the removed value is kept for a following Added.
+func (t *Tracker) Removed(root interface{}, path []string) {
+	if t == nil || len(path) == 0 {
+		return
+	}
+	parent, po, ok := t.locate(root, path[:len(path)-1])
+	if !ok {
+		return
+	}
+	last := path[len(path)-1]
+	switch p := parent.(type) {
+	case *ordered.Map:
+		t.removed = t.get(p, last)
+		t.remove(p, last)
+	case []interface{}:
+		i, err := strconv.Atoi(last)
+		if po == nil || err != nil || i >= len(po.Elems) {
+			t.removed = nil
+			return
+		}
+		t.removed = po.Elems[i]
+		po.Elems = append(po.Elems[:i:i], po.Elems[i+1:]...)
+	}
+}
+
+// Added should be called after a value is added at path of root.
+// If moved, the value keeps the origin recorded by the last Removed,
+// otherwise it comes from source entirely.
+func (t *Tracker) Added(root interface{}, path []string, source string, moved bool) {
+	if t == nil {
+		return
+	}
+	value, err := ordered.Get(root, normalizePath(root, path))
+	if err != nil {
+		return
+	}
+	o := t.removed
+	if !moved || o == nil {
+		o = newOrigin(value, source)
+		t.recordChildren(value, source)
+	}
+	if len(path) == 0 {
+		return
+	}
+	parent, po, ok := t.locate(root, path[:len(path)-1])
+	if !ok {
+		return
+	}
+	last := path[len(path)-1]
+	switch p := parent.(type) {
+	case *ordered.Map:
+		t.set(p, last, o)
+	case []interface{}:
+		if po == nil {
+			return
+		}
+		i := len(p) - 1
+		if last != "-" {
+			i, _ = strconv.Atoi(last)
+		}
+		elems := po.elems(len(p) - 1)
+		elems = append(elems, nil)
+		copy(elems[i+1:], elems[i:])
+		elems[i] = o
+		po.Elems = elems
+	}
+}
+
+// locate finds the value at path of root, and its origin if it's
+// an array, since origins of array elements are held by the array.
+func (t *Tracker) locate(root interface{}, path []string) (interface{}, *origin, bool) {
+	var (
+		node interface{} = root
+		o    *origin
+	)
+	for _, token := range path {
+		switch n := node.(type) {
+		case *ordered.Map:
+			v, ok := n.Values[token]
+			if !ok {
+				return nil, nil, false
+			}
+			node, o = v, t.get(n, token)
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(n) {
+				return nil, nil, false
+			}
+			node = n[i]
+			if o != nil && i < len(o.Elems) {
+				o = o.Elems[i]
+			} else {
+				o = nil
+			}
+		default:
+			return nil, nil, false
+		}
+	}
+	return node, o, true
+}
+
+// normalizePath replaces the trailing "-" of path with the last index
+func normalizePath(root interface{}, path []string) []string {
+	if len(path) == 0 || path[len(path)-1] != "-" {
+		return path
+	}
+	parent, err := ordered.Get(root, path[:len(path)-1])
+	if err != nil {
+		return path
+	}
+	s, ok := parent.([]interface{})
+	if !ok || len(s) == 0 {
+		return path
+	}
+	return pointer.Append(path[:len(path)-1], strconv.Itoa(len(s)-1))
+}
+
+// Provenance returns the sources of all leaves and array elements
+// of root, keyed by JSON pointers.
+func (t *Tracker) Provenance(root *ordered.Map) map[string]string {
+	r := make(map[string]string)
+	if t == nil {
+		return r
+	}
+	var walk func(path []string, v interface{}, o *origin)
+	walk = func(path []string, v interface{}, o *origin) {
+		switch v := v.(type) {
+		case *ordered.Map:
+			for _, k := range v.Keys {
+				walk(pointer.Append(path, k), v.Values[k], t.get(v, k))
+			}
+		case []interface{}:
+			for i, e := range v {
+				var eo *origin
+				if o != nil && i < len(o.Elems) {
+					eo = o.Elems[i]
+				}
+				p := pointer.Append(path, strconv.Itoa(i))
+				if eo != nil {
+					r[pointer.Format(p)] = eo.Source
+				}
+				walk(p, e, eo)
+			}
+		default:
+			if o != nil {
+				r[pointer.Format(path)] = o.Source
+			}
+		}
+	}
+	walk(nil, root, nil)
+	return r
+}
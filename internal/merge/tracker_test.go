@@ -0,0 +1,60 @@
+package merge_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/qjebbs/go-jsons/internal/merge"
+	"github.com/qjebbs/go-jsons/internal/ordered"
+	"github.com/qjebbs/go-jsons/internal/pointer"
+)
+
+func TestTracker(t *testing.T) {
+	t.Parallel()
+	prepend, _ := pointer.ParsePattern("/prepend")
+	byIndex, _ := pointer.ParsePattern("/index")
+	union, _ := pointer.ParsePattern("/union")
+	tracker := merge.NewTracker()
+	opts := &merge.Options{
+		Tracker:         tracker,
+		DirectivePrefix: "$",
+		ArrayRules: []merge.ArrayRule{
+			{Pattern: prepend, Strategy: merge.ArrayPrepend},
+			{Pattern: byIndex, Strategy: merge.ArrayMergeByIndex},
+			{Pattern: union, Strategy: merge.ArrayUnion},
+		},
+	}
+	values := convertToOrderedMaps(t, []string{
+		`{"a": 1, "prepend": [1], "index": [{"a": 1}, 2], "union": [1, 2], "directive": [1], "replace": {"a": 1}}`,
+		`{"a": 2, "prepend": [2], "index": [{"b": 1}], "union": [2, 3], "directive": {"$prepend": [2]}, "replace": {"$replace": true, "b": 1}}`,
+	})
+	target := ordered.New()
+	for i, v := range values {
+		opts.Source = []string{"a", "b"}[i]
+		if err := opts.OrderedMaps(target, []*ordered.Map{v}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := map[string]string{
+		"/a":           "b",
+		"/prepend/0":   "b",
+		"/prepend/1":   "a",
+		"/index/0":     "b",
+		"/index/0/a":   "a",
+		"/index/0/b":   "b",
+		"/index/1":     "a",
+		"/union/0":     "a",
+		"/union/1":     "a",
+		"/union/2":     "b",
+		"/directive/0": "b",
+		"/directive/1": "a",
+		"/replace/b":   "b",
+	}
+	got := tracker.Provenance(target)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want:\n%v\n\ngot:\n%v", want, got)
+	}
+	if src, ok := tracker.Source(target, "a"); !ok || src != "b" {
+		t.Errorf("want source b, got %q", src)
+	}
+}
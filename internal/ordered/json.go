@@ -3,7 +3,9 @@ package ordered
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 )
 
 // MarshalJSON implements the json.Marshaler interface.
@@ -30,14 +32,68 @@ func (o Map) MarshalJSON() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+var (
+	// ErrTooDeep is the error of values nested deeper than DecodeOptions.MaxDepth
+	ErrTooDeep = errors.New("max depth exceeded")
+	// ErrTooManyKeys is the error of more keys than DecodeOptions.MaxKeys
+	ErrTooManyKeys = errors.New("max keys exceeded")
+)
+
+// DecodeOptions is the options of decoding JSON into *Map.
+type DecodeOptions struct {
+	// UseNumber decodes numbers as json.Number to keep their precision
+	UseNumber bool
+	// MaxDepth is the max depth of nested objects and arrays,
+	// where the root object is at depth 1. 0 for unlimited.
+	MaxDepth int
+	// MaxKeys is the max number of keys of all objects, 0 for unlimited.
+	MaxKeys int
+	// Keys is the number of keys decoded before, which counts against MaxKeys
+	Keys int
+}
+
+// LimitError is the error of exceeding a limit of DecodeOptions,
+// which stops the decoding at once.
+type LimitError struct {
+	// Path is the path of the object or array exceeding the limit
+	Path []string
+	// Offset is the input offset where the limit is exceeded
+	Offset int64
+	Err    error
+}
+
+// Error implements the error interface.
+func (e *LimitError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
 // UnmarshalJSON implements the json.Unmarshaler interface.
+//
+// It decodes data in a single pass of the token stream, building
+// the nested *Map and []interface{} values in one go.
 func (o *Map) UnmarshalJSON(data []byte) error {
-	if o.Values == nil {
-		o.Values = map[string]interface{}{}
-	}
+	return o.UnmarshalJSONWith(data, DecodeOptions{})
+}
 
-	dec := json.NewDecoder(bytes.NewReader(data))
+// UnmarshalJSONUseNumber decodes data like UnmarshalJSON does,
+// with numbers decoded as json.Number to keep their precision.
+func (o *Map) UnmarshalJSONUseNumber(data []byte) error {
+	return o.UnmarshalJSONWith(data, DecodeOptions{UseNumber: true})
+}
 
+// UnmarshalJSONWith decodes data like UnmarshalJSON does, with the
+// options. The limits are checked while decoding, so that the decoding
+// stops at once with a *LimitError if any is exceeded.
+func (o *Map) UnmarshalJSONWith(data []byte, opts DecodeOptions) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if opts.UseNumber {
+		dec.UseNumber()
+	}
 	token, err := dec.Token()
 	if err != nil {
 		return err
@@ -49,6 +105,8 @@ func (o *Map) UnmarshalJSON(data []byte) error {
 		}
 	} else if num, ok := token.(float64); ok {
 		return fmt.Errorf("json: cannot unmarshal number %v into Go value of type %T", num, o)
+	} else if num, ok := token.(json.Number); ok {
+		return fmt.Errorf("json: cannot unmarshal number %v into Go value of type %T", num, o)
 	} else if str, ok := token.(string); ok {
 		return fmt.Errorf("json: cannot unmarshal string %q into Go value of type %T", str, o)
 	} else if b, ok := token.(bool); ok {
@@ -56,20 +114,71 @@ func (o *Map) UnmarshalJSON(data []byte) error {
 	} else if token == nil {
 		return fmt.Errorf("json: cannot unmarshal null into Go value of type %T", o)
 	}
-
+	if o.Values == nil {
+		o.Values = map[string]interface{}{}
+	}
 	o.Keys = make([]string, 0)
-	hasKey := make(map[string]bool)
+	d := &decoder{
+		dec:     dec,
+		opts:    opts,
+		limited: opts.MaxDepth > 0 || opts.MaxKeys > 0,
+		keys:    opts.Keys,
+	}
+	return d.decodeObject(o)
+}
+
+// decoder decodes the token stream of dec with the options
+type decoder struct {
+	dec  *json.Decoder
+	opts DecodeOptions
+	// limited tells if any limit is set, where the path is tracked
+	limited bool
+	// path is the path of the value being decoded if limited
+	path []string
+	// keys is the number of keys decoded
+	keys int
+}
+
+// push enters the value of token if limited
+func (d *decoder) push(token string) {
+	if d.limited {
+		d.path = append(d.path, token)
+	}
+}
+
+// pop leaves the value entered by push
+func (d *decoder) pop() {
+	if d.limited {
+		d.path = d.path[:len(d.path)-1]
+	}
+}
+
+// limitError returns the *LimitError of err at the current path
+func (d *decoder) limitError(err error, limit int) error {
+	path := make([]string, len(d.path))
+	copy(path, d.path)
+	return &LimitError{
+		Path:   path,
+		Offset: d.dec.InputOffset(),
+		Err:    fmt.Errorf("%w: %d", err, limit),
+	}
+}
 
-	for dec.More() {
+// decodeObject decodes the fields of an object into o, after its '{'
+// is read, until its '}' is read.
+func (d *decoder) decodeObject(o *Map) error {
+	for d.dec.More() {
 		// read key
-		token, err := dec.Token()
+		token, err := d.dec.Token()
 		if err != nil {
 			return err
 		}
-		key := token.(string)
-
+		key, ok := token.(string)
+		if !ok {
+			return fmt.Errorf("expected object key, got %v", token)
+		}
 		// duplicate key, remove previous occurrence
-		if hasKey[key] {
+		if _, exists := o.Values[key]; exists {
 			for j, k := range o.Keys {
 				if k == key {
 					copy(o.Keys[j:], o.Keys[j+1:])
@@ -78,96 +187,84 @@ func (o *Map) UnmarshalJSON(data []byte) error {
 				}
 			}
 		} else {
-			hasKey[key] = true
+			d.keys++
+			if limit := d.opts.MaxKeys; limit > 0 && d.keys > limit {
+				return d.limitError(ErrTooManyKeys, limit)
+			}
 		}
 		o.Keys = append(o.Keys, key)
 
 		// read value
-		var value shallowParser
-		if err := dec.Decode(&value); err != nil {
-			return err
-		}
-		v, err := o.processNestedValue(value)
+		d.push(key)
+		value, err := d.decodeValue()
 		if err != nil {
 			return err
 		}
-		o.Values[key] = v
+		d.pop()
+		o.Values[key] = value
 	}
-
 	// expect '}'
-	token, err = dec.Token()
+	token, err := d.dec.Token()
 	if err != nil {
 		return err
 	}
 	if delim, ok := token.(json.Delim); !ok || delim != '}' {
 		return fmt.Errorf("expected end of object, got %v", token)
 	}
-
 	return nil
 }
 
-func (o *Map) processNestedValue(value shallowParser) (interface{}, error) {
-	if value.Val != nil {
-		var r interface{}
-		if err := json.Unmarshal(value.Val, &r); err != nil {
-			return nil, err
-		}
-		return r, nil
-	}
-	if value.Obj != nil {
-		nested := &Map{}
-		if err := nested.UnmarshalJSON(value.Obj); err != nil {
-			return nil, err
+// decodeArray decodes the elements of an array, after its '['
+// is read, until its ']' is read.
+func (d *decoder) decodeArray() ([]interface{}, error) {
+	arr := make([]interface{}, 0)
+	for d.dec.More() {
+		if d.limited {
+			d.push(strconv.Itoa(len(arr)))
 		}
-		return nested, nil
-	}
-	r := make([]interface{}, len(value.Arr))
-	for i, item := range value.Arr {
-		var sv shallowParser
-		if err := json.Unmarshal(item, &sv); err != nil {
-			return nil, err
-		}
-		v, err := o.processNestedValue(sv)
+		value, err := d.decodeValue()
 		if err != nil {
 			return nil, err
 		}
-		r[i] = v
+		d.pop()
+		arr = append(arr, value)
 	}
-	return r, nil
-}
-
-var _ json.Unmarshaler = &Map{}
-
-type shallowParser struct {
-	Obj json.RawMessage
-	Arr []json.RawMessage
-	Val json.RawMessage
+	// expect ']'
+	token, err := d.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != ']' {
+		return nil, fmt.Errorf("expected end of array, got %v", token)
+	}
+	return arr, nil
 }
 
-func (s *shallowParser) UnmarshalJSON(data []byte) error {
-	dec := json.NewDecoder(bytes.NewReader(data))
-	token, err := dec.Token()
+// decodeValue decodes the next value of dec at the current path
+func (d *decoder) decodeValue() (interface{}, error) {
+	token, err := d.dec.Token()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if delim, ok := token.(json.Delim); ok {
-		switch delim {
-		case '{':
-			s.Obj = data
-		case '[':
-			for dec.More() {
-				var raw json.RawMessage
-				if err := dec.Decode(&raw); err != nil {
-					return err
-				}
-				s.Arr = append(s.Arr, raw)
-			}
-			// expect ']'
-			_, err = dec.Token()
-			return err
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+	if limit := d.opts.MaxDepth; limit > 0 && len(d.path) >= limit {
+		return nil, d.limitError(ErrTooDeep, limit)
+	}
+	switch delim {
+	case '{':
+		nested := New()
+		if err := d.decodeObject(nested); err != nil {
+			return nil, err
 		}
-		return nil
+		return nested, nil
+	case '[':
+		return d.decodeArray()
+	default:
+		return nil, fmt.Errorf("unexpected delimiter %v", delim)
 	}
-	s.Val = data
-	return nil
 }
+
+var _ json.Unmarshaler = &Map{}
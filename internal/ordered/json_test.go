@@ -0,0 +1,169 @@
+package ordered_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/qjebbs/go-jsons/internal/ordered"
+)
+
+func TestOrderedUnmarshalNested(t *testing.T) {
+	raw := []byte(`{"b":[1,{"d":[[],{}],"c":null},"s"],"a":{"y":true,"x":{"z":1.5}},"e":[]}`)
+	o := ordered.New()
+	if err := json.Unmarshal(raw, o); err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, raw) {
+		t.Errorf("want %s, got %s", raw, got)
+	}
+	arr := o.Values["b"].([]interface{})
+	if _, ok := arr[1].(*ordered.Map); !ok {
+		t.Errorf("want *ordered.Map, got %T", arr[1])
+	}
+	if _, ok := o.Values["e"].([]interface{}); !ok {
+		t.Errorf("want []interface{}, got %T", o.Values["e"])
+	}
+}
+
+func TestOrderedUnmarshalInvalid(t *testing.T) {
+	for _, raw := range []string{`{"a":}`, `{"a":[1,}`, `{"a":{"b"}}`, `{"a":1`, ``} {
+		if err := ordered.New().UnmarshalJSON([]byte(raw)); err == nil {
+			t.Errorf("%s: expected error, got nil", raw)
+		}
+	}
+}
+
+// largeConfig generates a config of at least size bytes
+func largeConfig(size int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`{"outbounds":[`)
+	for i := 0; buf.Len() < size; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(&buf, `{"tag":"proxy-%d","protocol":"vmess","settings":{"vnext":[{"address":"example.com","port":%d,"users":[{"id":"b831381d-6324-4d53-ad4f-8cda48b30811","alterId":0,"security":"auto"}]}]},"streamSettings":{"network":"ws","wsSettings":{"path":"/path","headers":{"Host":"example.com"}}}}`, i, i)
+	}
+	buf.WriteString(`]}`)
+	return buf.Bytes()
+}
+
+func BenchmarkUnmarshalJSON(b *testing.B) {
+	for _, size := range []int{1 << 10, 1 << 20, 10 << 20} {
+		data := largeConfig(size)
+		b.Run(fmt.Sprintf("%dKB", size>>10), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := ordered.New().UnmarshalJSON(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkUnmarshalJSONDeep(b *testing.B) {
+	const depth = 100
+	data := []byte(strings.Repeat(`{"a":[`, depth) + "1" + strings.Repeat(`]}`, depth))
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := ordered.New().UnmarshalJSON(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestOrderedUnmarshalUseNumber(t *testing.T) {
+	raw := []byte(`{"id":9007199254740993,"list":[1.50,{"n":-1e3}]}`)
+	o := ordered.New()
+	if err := o.UnmarshalJSONUseNumber(raw); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := o.Values["id"].(json.Number); !ok || got != "9007199254740993" {
+		t.Errorf("want json.Number 9007199254740993, got %T %v", o.Values["id"], o.Values["id"])
+	}
+	got, err := json.Marshal(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, raw) {
+		t.Errorf("want %s, got %s", raw, got)
+	}
+	if ordered.DeepEqual(json.Number("9007199254740993"), json.Number("9007199254740992")) {
+		t.Error("want big integers compared exactly")
+	}
+	if !ordered.DeepEqual(json.Number("1.50"), float64(1.5)) {
+		t.Error("want json.Number equal to float64 of the same value")
+	}
+	if err := ordered.New().UnmarshalJSONUseNumber([]byte(`1`)); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestOrderedUnmarshalLimits(t *testing.T) {
+	testCases := []struct {
+		name   string
+		raw    string
+		opts   ordered.DecodeOptions
+		want   error
+		path   []string
+		offset int64
+	}{
+		{
+			name: "depth",
+			raw:  `{"a":[1,{"b":[]}]}`,
+			opts: ordered.DecodeOptions{MaxDepth: 2},
+			want: ordered.ErrTooDeep, path: []string{"a", "1"}, offset: 9,
+		},
+		{
+			name: "depth ok",
+			raw:  `{"a":[1,{"b":[]}]}`,
+			opts: ordered.DecodeOptions{MaxDepth: 4},
+		},
+		{
+			name: "keys",
+			raw:  `{"a":1,"b":{"c":1}}`,
+			opts: ordered.DecodeOptions{MaxKeys: 2},
+			want: ordered.ErrTooManyKeys, path: []string{"b"}, offset: 15,
+		},
+		{
+			name: "keys decoded before",
+			raw:  `{"a":1,"b":2}`,
+			opts: ordered.DecodeOptions{MaxKeys: 2, Keys: 1},
+			want: ordered.ErrTooManyKeys, path: []string{}, offset: 10,
+		},
+		{
+			name: "duplicate keys",
+			raw:  `{"a":1,"a":2}`,
+			opts: ordered.DecodeOptions{MaxKeys: 1},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ordered.New().UnmarshalJSONWith([]byte(tc.raw), tc.opts)
+			if tc.want == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var le *ordered.LimitError
+			if !errors.As(err, &le) || !errors.Is(err, tc.want) {
+				t.Fatalf("want %v, got %v", tc.want, err)
+			}
+			if !reflect.DeepEqual(le.Path, tc.path) || le.Offset != tc.offset {
+				t.Errorf("want %v at %d, got %v at %d", tc.path, tc.offset, le.Path, le.Offset)
+			}
+		})
+	}
+}
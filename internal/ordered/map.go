@@ -2,7 +2,11 @@ package ordered
 
 import (
 	"encoding/json"
+	"fmt"
 	"sort"
+	"strconv"
+
+	"github.com/qjebbs/go-jsons/internal/pointer"
 )
 
 var _ json.Marshaler = &Map{}
@@ -99,3 +103,178 @@ func (o *Map) Sort(less ...func(a, b string) bool) *Map {
 	}
 	return o
 }
+
+// Get returns the value of key, and whether the key exists.
+func (o *Map) Get(key string) (interface{}, bool) {
+	v, ok := o.Values[key]
+	return v, ok
+}
+
+// Has tells if the key exists.
+func (o *Map) Has(key string) bool {
+	_, ok := o.Values[key]
+	return ok
+}
+
+// Len returns the number of keys.
+func (o *Map) Len() int {
+	return len(o.Keys)
+}
+
+// Clone returns a deep copy of the Ordered object.
+func (o *Map) Clone() *Map {
+	return DeepCopy(o).(*Map)
+}
+
+// Equal tells if o and other have the same members in the same order,
+// where nested objects are compared in order too, and numbers are compared
+// by value. Use DeepEqual to compare regardless of the order.
+func (o *Map) Equal(other *Map) bool {
+	return equalInOrder(o, other)
+}
+
+func equalInOrder(a, b interface{}) bool {
+	switch a := a.(type) {
+	case *Map:
+		b, ok := b.(*Map)
+		if !ok || len(a.Keys) != len(b.Keys) {
+			return false
+		}
+		for i, k := range a.Keys {
+			if b.Keys[i] != k || !equalInOrder(a.Values[k], b.Values[k]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equalInOrder(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return DeepEqual(a, b)
+}
+
+// Range calls fn for each key and value in order, until fn returns false.
+// Keys can be set or removed in fn, which affects the rest of the iteration.
+func (o *Map) Range(fn func(key string, value interface{}) bool) {
+	keys := make([]string, len(o.Keys))
+	copy(keys, o.Keys)
+	for _, k := range keys {
+		v, ok := o.Values[k]
+		if !ok {
+			// removed in fn
+			continue
+		}
+		if !fn(k, v) {
+			return
+		}
+	}
+}
+
+// InsertAt sets the value of key, and moves the key to index of the keys,
+// where index less than 0 is the first, and index not less than Len is
+// the last.
+func (o *Map) InsertAt(index int, key string, value interface{}) {
+	o.Remove(key)
+	if index < 0 {
+		index = 0
+	}
+	if index > len(o.Keys) {
+		index = len(o.Keys)
+	}
+	o.Keys = append(o.Keys, "")
+	copy(o.Keys[index+1:], o.Keys[index:])
+	o.Keys[index] = key
+	o.Values[key] = value
+}
+
+// MoveBefore moves key before mark, and tells if both keys exist.
+func (o *Map) MoveBefore(key, mark string) bool {
+	if !o.Has(key) || !o.Has(mark) {
+		return false
+	}
+	if key == mark {
+		return true
+	}
+	value := o.Values[key]
+	o.Remove(key)
+	for i, k := range o.Keys {
+		if k == mark {
+			o.InsertAt(i, key, value)
+			break
+		}
+	}
+	return true
+}
+
+// GetPath returns the value at the JSON pointer ptr, e.g.: "/a/0/b".
+func (o *Map) GetPath(ptr string) (interface{}, error) {
+	path, err := pointer.Parse(ptr)
+	if err != nil {
+		return nil, err
+	}
+	return Get(o, path)
+}
+
+// SetPath sets the value at the JSON pointer ptr, creating the missing
+// objects on the way. Array elements on the way must exist, and the
+// value is appended to an array if its last token is "-" or the length.
+func (o *Map) SetPath(ptr string, value interface{}) error {
+	path, err := pointer.Parse(ptr)
+	if err != nil {
+		return err
+	}
+	if len(path) == 0 {
+		return fmt.Errorf("cannot set the whole document")
+	}
+	// create the missing objects
+	var node interface{} = o
+	for i, token := range path[:len(path)-1] {
+		m, ok := node.(*Map)
+		if ok && !m.Has(token) {
+			m.Set(token, New())
+		}
+		node, err = getChild(node, token)
+		if err != nil {
+			return fmt.Errorf("%s: %w", pointer.Format(path[:i+1]), err)
+		}
+	}
+	_, err = modify(o, path, func(parent interface{}, token string) (interface{}, error) {
+		switch p := parent.(type) {
+		case *Map:
+			p.Set(token, value)
+			return p, nil
+		case []interface{}:
+			if token == "-" || token == strconv.Itoa(len(p)) {
+				return append(p, value), nil
+			}
+			i, err := index(token, len(p))
+			if err != nil {
+				return nil, err
+			}
+			p[i] = value
+			return p, nil
+		default:
+			return nil, fmt.Errorf("cannot set in %T", parent)
+		}
+	}, nil)
+	return err
+}
+
+// DeletePath deletes the value at the JSON pointer ptr,
+// and returns the deleted value.
+func (o *Map) DeletePath(ptr string) (interface{}, error) {
+	path, err := pointer.Parse(ptr)
+	if err != nil {
+		return nil, err
+	}
+	_, removed, err := Remove(o, path)
+	return removed, err
+}
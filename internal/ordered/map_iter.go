@@ -0,0 +1,13 @@
+//go:build go1.23
+
+package ordered
+
+import "iter"
+
+// All returns an iterator over the keys and values in order,
+// which behaves like Range.
+func (o *Map) All() iter.Seq2[string, interface{}] {
+	return func(yield func(string, interface{}) bool) {
+		o.Range(yield)
+	}
+}
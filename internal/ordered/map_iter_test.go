@@ -0,0 +1,22 @@
+//go:build go1.23
+
+package ordered_test
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOrderedAll(t *testing.T) {
+	o := mustParse(t, `{"b":1,"a":2,"c":3}`)
+	var keys []string
+	for k := range o.All() {
+		if k == "c" {
+			break
+		}
+		keys = append(keys, k)
+	}
+	if want := []string{"b", "a"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("want %v, got %v", want, keys)
+	}
+}
@@ -111,3 +111,138 @@ func TestOrderedSetRemove(t *testing.T) {
 		t.Errorf("Set or Remove result mismatch, want: %+v, got: %+v", want, o)
 	}
 }
+
+func TestOrderedAccessors(t *testing.T) {
+	o := mustParse(t, `{"a":1,"b":{"c":[1,2]}}`)
+	if v, ok := o.Get("a"); !ok || v != float64(1) {
+		t.Errorf("Get: want 1, got %v", v)
+	}
+	if _, ok := o.Get("x"); ok {
+		t.Error("Get: want not found")
+	}
+	if !o.Has("b") || o.Has("x") {
+		t.Error("Has mismatch")
+	}
+	if o.Len() != 2 {
+		t.Errorf("Len: want 2, got %d", o.Len())
+	}
+	c := o.Clone()
+	if !c.Equal(o) {
+		t.Error("Clone: want equal")
+	}
+	if err := c.SetPath("/b/c/0", 0); err != nil {
+		t.Fatal(err)
+	}
+	if c.Equal(o) {
+		t.Error("Clone: want deep copy")
+	}
+	// order matters
+	if mustParse(t, `{"a":1,"b":2}`).Equal(mustParse(t, `{"b":2,"a":1}`)) {
+		t.Error("Equal: want key order compared")
+	}
+	if !mustParse(t, `{"a":[{"b":1}]}`).Equal(mustParse(t, `{"a":[{"b":1}]}`)) {
+		t.Error("Equal: want equal")
+	}
+}
+
+func TestOrderedRange(t *testing.T) {
+	o := mustParse(t, `{"a":1,"b":2,"c":3,"d":4}`)
+	var keys []string
+	o.Range(func(key string, value interface{}) bool {
+		keys = append(keys, key)
+		if key == "a" {
+			o.Remove("b")
+		}
+		return key != "c"
+	})
+	if want := []string{"a", "c"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("want %v, got %v", want, keys)
+	}
+}
+
+func TestOrderedInsertMove(t *testing.T) {
+	o := mustParse(t, `{"a":1,"b":2,"c":3}`)
+	o.InsertAt(1, "x", 0)
+	o.InsertAt(-1, "c", 4)
+	o.InsertAt(100, "y", 5)
+	assertKeys(t, o, "c", "a", "x", "b", "y")
+	if o.Values["c"] != 4 {
+		t.Errorf("want c updated, got %v", o.Values["c"])
+	}
+	if !o.MoveBefore("y", "a") {
+		t.Error("MoveBefore: want true")
+	}
+	assertKeys(t, o, "c", "y", "a", "x", "b")
+	if !o.MoveBefore("c", "b") {
+		t.Error("MoveBefore: want true")
+	}
+	assertKeys(t, o, "y", "a", "x", "c", "b")
+	if o.MoveBefore("z", "a") || o.MoveBefore("a", "z") {
+		t.Error("MoveBefore: want false for missing keys")
+	}
+}
+
+func TestOrderedPath(t *testing.T) {
+	o := mustParse(t, `{"a":{"b":[{"c":1}]}}`)
+	v, err := o.GetPath("/a/b/0/c")
+	if err != nil || v != float64(1) {
+		t.Errorf("GetPath: want 1, got %v, %v", v, err)
+	}
+	if _, err := o.GetPath("/a/x"); err == nil {
+		t.Error("GetPath: want error")
+	}
+	for _, tc := range []struct {
+		ptr   string
+		value interface{}
+	}{
+		{"/a/b/0/c", 2},
+		{"/a/b/-", 3},
+		{"/a/b/2", 4},
+		{"/x/y/z", 5},
+	} {
+		if err := o.SetPath(tc.ptr, tc.value); err != nil {
+			t.Fatalf("SetPath %s: %v", tc.ptr, err)
+		}
+	}
+	for _, ptr := range []string{"", "/a/b/5", "/a/b/9/c", "/a/b/0/c/d", "/a/b/+0/c", "/a/b/-0/c"} {
+		if err := o.SetPath(ptr, 0); err == nil {
+			t.Errorf("SetPath %q: want error", ptr)
+		}
+	}
+	removed, err := o.DeletePath("/a/b/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 3 {
+		t.Errorf("DeletePath: want 3, got %v", removed)
+	}
+	if _, err := o.DeletePath(""); err == nil {
+		t.Error("DeletePath: want error for the whole document")
+	}
+	got, err := json.Marshal(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"a":{"b":[{"c":2},4]},"x":{"y":{"z":5}}}`; string(got) != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
+
+func mustParse(t *testing.T, s string) *ordered.Map {
+	t.Helper()
+	o := ordered.New()
+	if err := json.Unmarshal([]byte(s), o); err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func assertKeys(t *testing.T, o *ordered.Map, keys ...string) {
+	t.Helper()
+	if !reflect.DeepEqual(o.Keys, keys) {
+		t.Errorf("want keys %v, got %v", keys, o.Keys)
+	}
+	if len(o.Values) != len(keys) {
+		t.Errorf("want %d values, got %d", len(keys), len(o.Values))
+	}
+}
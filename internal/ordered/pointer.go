@@ -0,0 +1,189 @@
+package ordered
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/qjebbs/go-jsons/internal/pointer"
+)
+
+// Get returns the value referenced by the path tokens in doc.
+func Get(doc interface{}, path []string) (interface{}, error) {
+	node := doc
+	for i, token := range path {
+		child, err := getChild(node, token)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", pointer.Format(path[:i+1]), err)
+		}
+		node = child
+	}
+	return node, nil
+}
+
+// Add adds the value at path as the "add" operation of RFC 6902 does:
+// an existing object member is replaced, and an array element is inserted
+// before the index, where "-" means appending to the array.
+//
+// It returns the updated document, since arrays or the root could be replaced.
+func Add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	return modify(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch p := parent.(type) {
+		case *Map:
+			p.Set(token, value)
+			return p, nil
+		case []interface{}:
+			if token == "-" {
+				return append(p, value), nil
+			}
+			i, err := index(token, len(p)+1)
+			if err != nil {
+				return nil, err
+			}
+			p = append(p, nil)
+			copy(p[i+1:], p[i:])
+			p[i] = value
+			return p, nil
+		default:
+			return nil, fmt.Errorf("cannot add to %T", parent)
+		}
+	}, value)
+}
+
+// Replace replaces the existing value at path with value,
+// and returns the updated document.
+func Replace(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	return modify(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch p := parent.(type) {
+		case *Map:
+			if _, ok := p.Values[token]; !ok {
+				return nil, fmt.Errorf("member %q not found", token)
+			}
+			p.Values[token] = value
+			return p, nil
+		case []interface{}:
+			i, err := index(token, len(p))
+			if err != nil {
+				return nil, err
+			}
+			p[i] = value
+			return p, nil
+		default:
+			return nil, fmt.Errorf("cannot replace in %T", parent)
+		}
+	}, value)
+}
+
+// Remove removes the existing value at path, and returns the updated
+// document and the removed value.
+func Remove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("cannot remove the whole document")
+	}
+	var removed interface{}
+	doc, err := modify(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch p := parent.(type) {
+		case *Map:
+			v, ok := p.Values[token]
+			if !ok {
+				return nil, fmt.Errorf("member %q not found", token)
+			}
+			removed = v
+			p.Remove(token)
+			return p, nil
+		case []interface{}:
+			i, err := index(token, len(p))
+			if err != nil {
+				return nil, err
+			}
+			removed = p[i]
+			return append(p[:i:i], p[i+1:]...), nil
+		default:
+			return nil, fmt.Errorf("cannot remove from %T", parent)
+		}
+	}, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return doc, removed, nil
+}
+
+// modify walks to the parent of path and replaces it with the result of fn,
+// then writes the updated containers back up to the root.
+// An empty path replaces the whole document with root.
+func modify(
+	doc interface{}, path []string,
+	fn func(parent interface{}, token string) (interface{}, error),
+	root interface{},
+) (interface{}, error) {
+	if len(path) == 0 {
+		return root, nil
+	}
+	var walk func(node interface{}, depth int) (interface{}, error)
+	walk = func(node interface{}, depth int) (interface{}, error) {
+		token := path[depth]
+		if depth == len(path)-1 {
+			n, err := fn(node, token)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", pointer.Format(path), err)
+			}
+			return n, nil
+		}
+		child, err := getChild(node, token)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", pointer.Format(path[:depth+1]), err)
+		}
+		child, err = walk(child, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		switch n := node.(type) {
+		case *Map:
+			n.Values[token] = child
+		case []interface{}:
+			i, _ := index(token, len(n))
+			n[i] = child
+		}
+		return node, nil
+	}
+	return walk(doc, 0)
+}
+
+func getChild(node interface{}, token string) (interface{}, error) {
+	switch n := node.(type) {
+	case *Map:
+		v, ok := n.Values[token]
+		if !ok {
+			return nil, fmt.Errorf("member %q not found", token)
+		}
+		return v, nil
+	case []interface{}:
+		i, err := index(token, len(n))
+		if err != nil {
+			return nil, err
+		}
+		return n[i], nil
+	default:
+		return nil, fmt.Errorf("cannot get %q from %T", token, node)
+	}
+}
+
+// index parses an array index token, which must be less than size.
+// As RFC 6901 requires, the token is "0" or digits without a leading zero.
+func index(token string, size int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	for _, c := range token {
+		if c < '0' || c > '9' {
+			return 0, fmt.Errorf("invalid array index %q", token)
+		}
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if i >= size {
+		return 0, fmt.Errorf("array index %d out of range", i)
+	}
+	return i, nil
+}
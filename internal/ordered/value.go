@@ -0,0 +1,119 @@
+package ordered
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// DeepCopy returns a deep copy of the JSON value v.
+func DeepCopy(v interface{}) interface{} {
+	switch v := v.(type) {
+	case *Map:
+		if v == nil {
+			return v
+		}
+		m := &Map{
+			Values: make(map[string]interface{}, len(v.Values)),
+			Keys:   make([]string, len(v.Keys)),
+		}
+		copy(m.Keys, v.Keys)
+		for k, value := range v.Values {
+			m.Values[k] = DeepCopy(value)
+		}
+		return m
+	case []interface{}:
+		if v == nil {
+			return v
+		}
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = DeepCopy(e)
+		}
+		return s
+	default:
+		return v
+	}
+}
+
+// DeepEqual tells if JSON values a and b are equal.
+// Objects are equal if they have the same members regardless of the order,
+// and numbers are compared by value regardless of their Go types,
+// including json.Number.
+func DeepEqual(a, b interface{}) bool {
+	switch a := a.(type) {
+	case *Map:
+		b, ok := b.(*Map)
+		if !ok || len(a.Values) != len(b.Values) {
+			return false
+		}
+		for k, va := range a.Values {
+			vb, ok := b.Values[k]
+			if !ok || !DeepEqual(va, vb) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !DeepEqual(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	if na, ok := a.(json.Number); ok {
+		// compare big integers exactly
+		if nb, ok := b.(json.Number); ok {
+			ia, errA := na.Int64()
+			ib, errB := nb.Int64()
+			if errA == nil && errB == nil {
+				return ia == ib
+			}
+		}
+	}
+	if na, ok := ToFloat(a); ok {
+		nb, ok := ToFloat(b)
+		return ok && na == nb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// ToFloat returns v as a float64 if it's a number, which is a float,
+// an integer or a json.Number.
+func ToFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,250 @@
+// Package patch implements JSON Patch (RFC 6902) over ordered maps.
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/qjebbs/go-jsons/internal/ordered"
+	"github.com/qjebbs/go-jsons/internal/pointer"
+)
+
+// ErrTestFailed is the error of a failed "test" operation.
+var ErrTestFailed = errors.New("test failed")
+
+// Error is the error of a patch operation
+type Error struct {
+	Index int    // index of the operation in the patch
+	Op    string // operation name
+	Path  string // target path of the operation
+	Err   error
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("operation %d (%s %q): %s", e.Index, e.Op, e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Operation is a JSON Patch operation.
+type Operation struct {
+	Op    string
+	Path  []string
+	From  []string
+	Value interface{}
+
+	path string
+	from string
+}
+
+// NewOperation returns the operation op with value at path.
+func NewOperation(op string, path []string, value interface{}) *Operation {
+	return &Operation{
+		Op:    op,
+		Path:  path,
+		Value: value,
+		path:  pointer.Format(path),
+	}
+}
+
+// Patch is a JSON Patch document.
+type Patch []*Operation
+
+// Parse parses a JSON Patch document.
+func Parse(data []byte) (Patch, error) {
+	var ops []*ordered.Map
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return nil, err
+	}
+	return parseOperations(ops)
+}
+
+// ParseUseNumber parses data like Parse does, with numbers
+// decoded as json.Number to keep their precision.
+func ParseUseNumber(data []byte) (Patch, error) {
+	var nops []*numberMap
+	if err := json.Unmarshal(data, &nops); err != nil {
+		return nil, err
+	}
+	ops := make([]*ordered.Map, len(nops))
+	for i, m := range nops {
+		ops[i] = (*ordered.Map)(m)
+	}
+	return parseOperations(ops)
+}
+
+// numberMap is an ordered.Map which decodes numbers as json.Number
+type numberMap ordered.Map
+
+func (m *numberMap) UnmarshalJSON(b []byte) error {
+	return (*ordered.Map)(m).UnmarshalJSONUseNumber(b)
+}
+
+func parseOperations(ops []*ordered.Map) (Patch, error) {
+	patch := make(Patch, 0, len(ops))
+	for i, m := range ops {
+		op, err := parseOperation(m)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+		patch = append(patch, op)
+	}
+	return patch, nil
+}
+
+func parseOperation(m *ordered.Map) (*Operation, error) {
+	if m == nil {
+		return nil, errors.New("operation must be an object")
+	}
+	var (
+		op  = &Operation{}
+		err error
+	)
+	if op.Op, err = getString(m, "op"); err != nil {
+		return nil, err
+	}
+	if op.path, err = getString(m, "path"); err != nil {
+		return nil, err
+	}
+	if op.Path, err = pointer.Parse(op.path); err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		value, ok := m.Values["value"]
+		if !ok {
+			return nil, fmt.Errorf("missing 'value' for %q", op.Op)
+		}
+		op.Value = value
+	case "move", "copy":
+		if op.from, err = getString(m, "from"); err != nil {
+			return nil, err
+		}
+		if op.From, err = pointer.Parse(op.from); err != nil {
+			return nil, err
+		}
+	case "remove":
+	default:
+		return nil, fmt.Errorf("unknown op %q", op.Op)
+	}
+	return op, nil
+}
+
+func getString(m *ordered.Map, key string) (string, error) {
+	v, ok := m.Values[key]
+	if !ok {
+		return "", fmt.Errorf("missing '%s'", key)
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("'%s' must be a string, got %T", key, v)
+	}
+	return s, nil
+}
+
+// Observer observes the changes made by patch operations
+type Observer interface {
+	// Removed is called after the value at path is removed from root
+	Removed(root interface{}, path []string)
+	// Added is called after a value is added at path of root,
+	// moved tells if it's moved from another place.
+	Added(root interface{}, path []string, moved bool)
+}
+
+// Apply applies the patch to the document in order.
+func (p Patch) Apply(doc *ordered.Map) error {
+	return p.ApplyObserved(doc, nil)
+}
+
+// ApplyObserved applies the patch to the document in order,
+// and reports the changes to the observer if not nil.
+func (p Patch) ApplyObserved(doc *ordered.Map, observer Observer) error {
+	var root interface{} = doc
+	for i, op := range p {
+		var err error
+		root, err = op.apply(root)
+		if err != nil {
+			return &Error{Index: i, Op: op.Op, Path: op.path, Err: err}
+		}
+		if observer != nil {
+			op.notify(root, observer)
+		}
+	}
+	if root == interface{}(doc) {
+		return nil
+	}
+	m, ok := root.(*ordered.Map)
+	if !ok {
+		return fmt.Errorf("patched document must be an object, got %T", root)
+	}
+	*doc = *m
+	if observer != nil {
+		// the root is replaced, values are now held by doc
+		observer.Added(doc, nil, false)
+	}
+	return nil
+}
+
+func (op *Operation) notify(root interface{}, observer Observer) {
+	switch op.Op {
+	case "add", "copy":
+		observer.Added(root, op.Path, false)
+	case "remove":
+		observer.Removed(root, op.Path)
+	case "replace":
+		observer.Removed(root, op.Path)
+		observer.Added(root, op.Path, false)
+	case "move":
+		if pointer.IsPrefix(op.From, op.Path) && len(op.From) == len(op.Path) {
+			// moved to itself
+			return
+		}
+		observer.Removed(root, op.From)
+		observer.Added(root, op.Path, true)
+	}
+}
+
+func (op *Operation) apply(doc interface{}) (interface{}, error) {
+	switch op.Op {
+	case "add":
+		return ordered.Add(doc, op.Path, ordered.DeepCopy(op.Value))
+	case "remove":
+		doc, _, err := ordered.Remove(doc, op.Path)
+		return doc, err
+	case "replace":
+		return ordered.Replace(doc, op.Path, ordered.DeepCopy(op.Value))
+	case "move":
+		if pointer.IsPrefix(op.From, op.Path) {
+			if len(op.From) == len(op.Path) {
+				return doc, nil
+			}
+			return nil, fmt.Errorf("cannot move %q into its child", op.from)
+		}
+		doc, value, err := ordered.Remove(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return ordered.Add(doc, op.Path, value)
+	case "copy":
+		value, err := ordered.Get(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return ordered.Add(doc, op.Path, ordered.DeepCopy(value))
+	case "test":
+		value, err := ordered.Get(doc, op.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !ordered.DeepEqual(value, op.Value) {
+			return nil, ErrTestFailed
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown op %q", op.Op)
+}
@@ -0,0 +1,172 @@
+package patch_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/qjebbs/go-jsons/internal/ordered"
+	"github.com/qjebbs/go-jsons/internal/patch"
+)
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{
+			name:  "add_member",
+			doc:   `{"foo":"bar"}`,
+			patch: `[{"op":"add","path":"/baz","value":"qux"}]`,
+			want:  `{"foo":"bar","baz":"qux"}`,
+		},
+		{
+			name:  "add_element",
+			doc:   `{"foo":["bar","baz"]}`,
+			patch: `[{"op":"add","path":"/foo/1","value":"qux"}]`,
+			want:  `{"foo":["bar","qux","baz"]}`,
+		},
+		{
+			name:  "append_element",
+			doc:   `{"foo":["bar"]}`,
+			patch: `[{"op":"add","path":"/foo/-","value":{"b":1,"a":2}}]`,
+			want:  `{"foo":["bar",{"b":1,"a":2}]}`,
+		},
+		{
+			name:  "remove",
+			doc:   `{"baz":"qux","foo":["bar","qux","baz"]}`,
+			patch: `[{"op":"remove","path":"/baz"},{"op":"remove","path":"/foo/1"}]`,
+			want:  `{"foo":["bar","baz"]}`,
+		},
+		{
+			name:  "replace",
+			doc:   `{"baz":"qux","foo":"bar"}`,
+			patch: `[{"op":"replace","path":"/baz","value":"boo"}]`,
+			want:  `{"baz":"boo","foo":"bar"}`,
+		},
+		{
+			name:  "move",
+			doc:   `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			patch: `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			want:  `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{
+			name:  "move_element",
+			doc:   `{"foo":["all","grass","cows","eat"]}`,
+			patch: `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+			want:  `{"foo":["all","cows","eat","grass"]}`,
+		},
+		{
+			name:  "copy",
+			doc:   `{"a":{"b":[1]}}`,
+			patch: `[{"op":"copy","from":"/a","path":"/c"},{"op":"add","path":"/c/b/-","value":2}]`,
+			want:  `{"a":{"b":[1]},"c":{"b":[1,2]}}`,
+		},
+		{
+			name:  "test",
+			doc:   `{"baz":"qux","foo":["a",2,"c"],"obj":{"x":1,"y":null}}`,
+			patch: `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2},{"op":"test","path":"/obj","value":{"y":null,"x":1}}]`,
+			want:  `{"baz":"qux","foo":["a",2,"c"],"obj":{"x":1,"y":null}}`,
+		},
+		{
+			name:  "escaped_path",
+			doc:   `{"a/b":{"m~n":1}}`,
+			patch: `[{"op":"replace","path":"/a~1b/m~0n","value":2}]`,
+			want:  `{"a/b":{"m~n":2}}`,
+		},
+		{
+			name:  "replace_root",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"replace","path":"","value":{"b":2}}]`,
+			want:  `{"b":2}`,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			doc := ordered.New()
+			if err := json.Unmarshal([]byte(tc.doc), doc); err != nil {
+				t.Fatal(err)
+			}
+			p, err := patch.Parse([]byte(tc.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Apply(doc); err != nil {
+				t.Fatal(err)
+			}
+			got, err := json.Marshal(doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("want:\n%s\ngot:\n%s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name  string
+		patch string
+		want  error
+	}{
+		{"test_failed", `[{"op":"test","path":"/a","value":2}]`, patch.ErrTestFailed},
+		{"remove_missing", `[{"op":"remove","path":"/b"}]`, nil},
+		{"replace_missing", `[{"op":"replace","path":"/b","value":1}]`, nil},
+		{"add_no_parent", `[{"op":"add","path":"/b/c","value":1}]`, nil},
+		{"index_out_of_range", `[{"op":"add","path":"/c/2","value":1}]`, nil},
+		{"leading_zero", `[{"op":"replace","path":"/c/00","value":1}]`, nil},
+		{"plus_sign", `[{"op":"replace","path":"/c/+0","value":1}]`, nil},
+		{"negative_zero", `[{"op":"replace","path":"/c/-0","value":1}]`, nil},
+		{"add_plus_sign", `[{"op":"add","path":"/c/+1","value":1}]`, nil},
+		{"move_into_child", `[{"op":"move","from":"/c","path":"/c/0"}]`, nil},
+		{"remove_root", `[{"op":"remove","path":""}]`, nil},
+		{"replace_root_array", `[{"op":"replace","path":"","value":[]}]`, nil},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			doc := ordered.New()
+			if err := json.Unmarshal([]byte(`{"a":1,"c":[1]}`), doc); err != nil {
+				t.Fatal(err)
+			}
+			p, err := patch.Parse([]byte(tc.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = p.Apply(doc)
+			if err == nil {
+				t.Fatal("want error, got nil")
+			}
+			if tc.want != nil && !errors.Is(err, tc.want) {
+				t.Errorf("want %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+	for _, s := range []string{
+		`{}`,
+		`[null]`,
+		`[{"path":"/a"}]`,
+		`[{"op":"add","path":"/a"}]`,
+		`[{"op":"add","path":1,"value":1}]`,
+		`[{"op":"add","path":"a","value":1}]`,
+		`[{"op":"move","path":"/a"}]`,
+		`[{"op":"unknown","path":"/a"}]`,
+	} {
+		if _, err := patch.Parse([]byte(s)); err == nil {
+			t.Errorf("parse %s: want error, got nil", s)
+		}
+	}
+}
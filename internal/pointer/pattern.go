@@ -0,0 +1,50 @@
+package pointer
+
+// Pattern is a JSON pointer pattern, where the token "*" matches
+// any single token, and "**" matches any number of tokens.
+type Pattern struct {
+	tokens []string
+}
+
+// ParsePattern parses a JSON pointer pattern, e.g. "/inbounds/*/settings/clients".
+func ParsePattern(s string) (*Pattern, error) {
+	tokens, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return &Pattern{tokens: tokens}, nil
+}
+
+// String returns the pattern string.
+func (p *Pattern) String() string {
+	return Format(p.tokens)
+}
+
+// Match tells if the path tokens match the pattern.
+func (p *Pattern) Match(path []string) bool {
+	return match(p.tokens, path)
+}
+
+func match(pattern, path []string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case "**":
+			for i := len(path); i >= 0; i-- {
+				if match(pattern[1:], path[i:]) {
+					return true
+				}
+			}
+			return false
+		case "*":
+			if len(path) == 0 {
+				return false
+			}
+		default:
+			if len(path) == 0 || pattern[0] != path[0] {
+				return false
+			}
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+	return len(path) == 0
+}
@@ -0,0 +1,67 @@
+// Package pointer implements JSON Pointer (RFC 6901) parsing and formatting.
+package pointer
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	unescaper = strings.NewReplacer("~1", "/", "~0", "~")
+	escaper   = strings.NewReplacer("~", "~0", "/", "~1")
+)
+
+// Parse parses a JSON pointer into reference tokens.
+// The empty string refers to the whole document and gives no tokens.
+func Parse(s string) ([]string, error) {
+	if s == "" {
+		return []string{}, nil
+	}
+	if s[0] != '/' {
+		return nil, fmt.Errorf("invalid JSON pointer %q: must start with '/'", s)
+	}
+	tokens := strings.Split(s[1:], "/")
+	for i, token := range tokens {
+		if strings.Contains(strings.ReplaceAll(strings.ReplaceAll(token, "~0", ""), "~1", ""), "~") {
+			return nil, fmt.Errorf("invalid JSON pointer %q: bad escape in %q", s, token)
+		}
+		tokens[i] = unescaper.Replace(token)
+	}
+	return tokens, nil
+}
+
+// Format formats reference tokens into a JSON pointer.
+func Format(tokens []string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteByte('/')
+		b.WriteString(escaper.Replace(token))
+	}
+	return b.String()
+}
+
+// Escape escapes a reference token.
+func Escape(token string) string {
+	return escaper.Replace(token)
+}
+
+// Append returns a new path with tokens appended,
+// which never shares the underlying array with path.
+func Append(path []string, tokens ...string) []string {
+	p := make([]string, len(path), len(path)+len(tokens))
+	copy(p, path)
+	return append(p, tokens...)
+}
+
+// IsPrefix tells if prefix is a prefix of tokens.
+func IsPrefix(prefix, tokens []string) bool {
+	if len(prefix) > len(tokens) {
+		return false
+	}
+	for i, token := range prefix {
+		if tokens[i] != token {
+			return false
+		}
+	}
+	return true
+}
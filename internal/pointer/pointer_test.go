@@ -0,0 +1,91 @@
+package pointer_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/qjebbs/go-jsons/internal/pointer"
+)
+
+func TestParseFormat(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		pointer string
+		tokens  []string
+	}{
+		{"", []string{}},
+		{"/", []string{""}},
+		{"/a/b", []string{"a", "b"}},
+		{"/a~1b/m~0n", []string{"a/b", "m~n"}},
+		{"/0/-", []string{"0", "-"}},
+	}
+	for _, tc := range testCases {
+		got, err := pointer.Parse(tc.pointer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tc.tokens, got) {
+			t.Errorf("parse %q: want %q, got %q", tc.pointer, tc.tokens, got)
+		}
+		if s := pointer.Format(got); s != tc.pointer {
+			t.Errorf("format %q: want %q, got %q", got, tc.pointer, s)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+	for _, s := range []string{"a", "/a~2", "/~"} {
+		if _, err := pointer.Parse(s); err == nil {
+			t.Errorf("parse %q: want error, got nil", s)
+		}
+	}
+}
+
+func TestAppend(t *testing.T) {
+	t.Parallel()
+	path := make([]string, 1, 4)
+	path[0] = "a"
+	b := pointer.Append(path, "b")
+	c := pointer.Append(path, "c", "d")
+	if got := pointer.Format(b); got != "/a/b" {
+		t.Errorf("want /a/b, got %s", got)
+	}
+	if got := pointer.Format(c); got != "/a/c/d" {
+		t.Errorf("want /a/c/d, got %s", got)
+	}
+}
+
+func TestPatternMatch(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"/route/rules", "/route/rules", true},
+		{"/route/rules", "/route/rules/0", false},
+		{"/route/rules", "/route", false},
+		{"/inbounds/*/settings/clients", "/inbounds/0/settings/clients", true},
+		{"/inbounds/*/settings/clients", "/inbounds/settings/clients", false},
+		{"/**/clients", "/clients", true},
+		{"/**/clients", "/inbounds/0/settings/clients", true},
+		{"/**", "/a/b", true},
+		{"/a/**/c", "/a/c", true},
+		{"/a/**/c", "/a/b/d", false},
+		{"", "", true},
+	}
+	for _, tc := range testCases {
+		p, err := pointer.ParsePattern(tc.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path, err := pointer.Parse(tc.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.Match(path); got != tc.want {
+			t.Errorf("%q match %q: want %v, got %v", tc.pattern, tc.path, tc.want, got)
+		}
+	}
+}
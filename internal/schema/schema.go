@@ -0,0 +1,628 @@
+// Package schema validates documents against a subset of JSON Schema
+// draft 2020-12.
+//
+// Supported keywords are:
+//
+//	type, enum, const
+//	properties, required, patternProperties, additionalProperties
+//	items, minItems, maxItems
+//	pattern, minLength, maxLength
+//	minimum, maximum, exclusiveMinimum, exclusiveMaximum
+//	$ref to the same document, $defs
+//
+// Other keywords are ignored.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/qjebbs/go-jsons/internal/ordered"
+	"github.com/qjebbs/go-jsons/internal/pointer"
+)
+
+// Violation is a violation of the schema at Path of the document.
+type Violation struct {
+	Path []string
+	Msg  string
+}
+
+// Schema is a compiled schema.
+type Schema struct {
+	// always is the result of a boolean schema, if not nil
+	always *bool
+
+	types    []string
+	enum     []interface{}
+	constant interface{}
+	hasConst bool
+
+	properties           map[string]*Schema
+	required             []string
+	patternProperties    []patternSchema
+	additionalProperties *Schema
+
+	items    *Schema
+	minItems *int
+	maxItems *int
+
+	pattern   *regexp.Regexp
+	minLength *int
+	maxLength *int
+
+	minimum          *number
+	maximum          *number
+	exclusiveMinimum *number
+	exclusiveMaximum *number
+
+	ref string
+	// resolved is the schema referenced by ref
+	resolved *Schema
+}
+
+type patternSchema struct {
+	pattern *regexp.Regexp
+	schema  *Schema
+}
+
+// compiler compiles schemas of a document
+type compiler struct {
+	root interface{}
+	// refs are the compiled schemas by $ref
+	refs map[string]*Schema
+	// pending are the schemas whose $ref to resolve
+	pending []*Schema
+}
+
+// Compile compiles the schema document b.
+func Compile(b []byte) (*Schema, error) {
+	// decode numbers as json.Number to keep big integers exact
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var root interface{}
+	if err := dec.Decode(&root); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid schema: unexpected data after the document")
+	}
+	c := &compiler{
+		root: root,
+		refs: make(map[string]*Schema),
+	}
+	s, err := c.compile(nil, root)
+	if err != nil {
+		return nil, err
+	}
+	c.refs["#"] = s
+	for len(c.pending) > 0 {
+		p := c.pending[0]
+		c.pending = c.pending[1:]
+		resolved, err := c.resolve(p.ref)
+		if err != nil {
+			return nil, err
+		}
+		p.resolved = resolved
+	}
+	for ref, s := range c.refs {
+		if err := checkRefCycle(s); err != nil {
+			return nil, fmt.Errorf("%s: %w", ref, err)
+		}
+	}
+	return s, nil
+}
+
+// resolve resolves the schema of ref, which is "#" followed by
+// a JSON pointer of the schema document.
+func (c *compiler) resolve(ref string) (*Schema, error) {
+	if s, ok := c.refs[ref]; ok {
+		return s, nil
+	}
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("unsupported $ref %q: only references within the schema are supported", ref)
+	}
+	path, err := pointer.Parse(ref[1:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid $ref %q: %w", ref, err)
+	}
+	v, ok := lookup(c.root, path)
+	if !ok {
+		return nil, fmt.Errorf("invalid $ref %q: not found", ref)
+	}
+	s, err := c.compile(path, v)
+	if err != nil {
+		return nil, err
+	}
+	c.refs[ref] = s
+	return s, nil
+}
+
+// checkRefCycle checks if s references itself without validating anything
+func checkRefCycle(s *Schema) error {
+	seen := make(map[*Schema]bool)
+	for s != nil && s.resolved != nil {
+		if seen[s] {
+			return errors.New("$ref cycle")
+		}
+		seen[s] = true
+		s = s.resolved
+	}
+	return nil
+}
+
+// compile compiles the schema v at path of the schema document
+func (c *compiler) compile(path []string, v interface{}) (*Schema, error) {
+	switch v := v.(type) {
+	case bool:
+		return &Schema{always: &v}, nil
+	case map[string]interface{}:
+		s := &Schema{}
+		if err := c.compileKeywords(path, s, v); err != nil {
+			return nil, err
+		}
+		return s, nil
+	default:
+		return nil, errorAt(path, "schema must be an object or a boolean, got %T", v)
+	}
+}
+
+func (c *compiler) compileKeywords(path []string, s *Schema, m map[string]interface{}) error {
+	var err error
+	if v, ok := m["type"]; ok {
+		if s.types, err = stringList(v); err != nil {
+			return errorAt(pointer.Append(path, "type"), "%s", err)
+		}
+	}
+	if v, ok := m["enum"]; ok {
+		enum, ok := v.([]interface{})
+		if !ok {
+			return errorAt(pointer.Append(path, "enum"), "expects an array")
+		}
+		s.enum = toOrdered(enum).([]interface{})
+	}
+	if v, ok := m["const"]; ok {
+		s.constant, s.hasConst = toOrdered(v), true
+	}
+	if v, ok := m["required"]; ok {
+		if s.required, err = stringList(v); err != nil {
+			return errorAt(pointer.Append(path, "required"), "%s", err)
+		}
+	}
+	if v, ok := m["properties"]; ok {
+		props, ok := v.(map[string]interface{})
+		if !ok {
+			return errorAt(pointer.Append(path, "properties"), "expects an object")
+		}
+		s.properties = make(map[string]*Schema, len(props))
+		for name, p := range props {
+			if s.properties[name], err = c.compile(pointer.Append(path, "properties", name), p); err != nil {
+				return err
+			}
+		}
+	}
+	if v, ok := m["patternProperties"]; ok {
+		props, ok := v.(map[string]interface{})
+		if !ok {
+			return errorAt(pointer.Append(path, "patternProperties"), "expects an object")
+		}
+		patterns := make([]string, 0, len(props))
+		for p := range props {
+			patterns = append(patterns, p)
+		}
+		sort.Strings(patterns)
+		for _, p := range patterns {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return errorAt(pointer.Append(path, "patternProperties", p), "%s", err)
+			}
+			ps, err := c.compile(pointer.Append(path, "patternProperties", p), props[p])
+			if err != nil {
+				return err
+			}
+			s.patternProperties = append(s.patternProperties, patternSchema{re, ps})
+		}
+	}
+	if v, ok := m["additionalProperties"]; ok {
+		if s.additionalProperties, err = c.compile(pointer.Append(path, "additionalProperties"), v); err != nil {
+			return err
+		}
+	}
+	if v, ok := m["items"]; ok {
+		if s.items, err = c.compile(pointer.Append(path, "items"), v); err != nil {
+			return err
+		}
+	}
+	if v, ok := m["pattern"]; ok {
+		p, ok := v.(string)
+		if !ok {
+			return errorAt(pointer.Append(path, "pattern"), "expects a string")
+		}
+		if s.pattern, err = regexp.Compile(p); err != nil {
+			return errorAt(pointer.Append(path, "pattern"), "%s", err)
+		}
+	}
+	for _, kw := range []struct {
+		name string
+		dst  **int
+	}{
+		{"minItems", &s.minItems},
+		{"maxItems", &s.maxItems},
+		{"minLength", &s.minLength},
+		{"maxLength", &s.maxLength},
+	} {
+		v, ok := m[kw.name]
+		if !ok {
+			continue
+		}
+		n, ok := toNumber(v)
+		if !ok || n.i == nil || n.i.Sign() < 0 || !n.i.IsInt64() {
+			return errorAt(pointer.Append(path, kw.name), "expects a non-negative integer")
+		}
+		i := int(n.i.Int64())
+		*kw.dst = &i
+	}
+	for _, kw := range []struct {
+		name string
+		dst  **number
+	}{
+		{"minimum", &s.minimum},
+		{"maximum", &s.maximum},
+		{"exclusiveMinimum", &s.exclusiveMinimum},
+		{"exclusiveMaximum", &s.exclusiveMaximum},
+	} {
+		v, ok := m[kw.name]
+		if !ok {
+			continue
+		}
+		n, ok := toNumber(v)
+		if !ok {
+			return errorAt(pointer.Append(path, kw.name), "expects a number")
+		}
+		*kw.dst = &n
+	}
+	if v, ok := m["$defs"]; ok {
+		defs, ok := v.(map[string]interface{})
+		if !ok {
+			return errorAt(pointer.Append(path, "$defs"), "expects an object")
+		}
+		// compile the definitions to report their errors early
+		for name := range defs {
+			ref := "#" + pointer.Format(pointer.Append(path, "$defs", name))
+			if _, err := c.resolve(ref); err != nil {
+				return err
+			}
+		}
+	}
+	if v, ok := m["$ref"]; ok {
+		ref, ok := v.(string)
+		if !ok {
+			return errorAt(pointer.Append(path, "$ref"), "expects a string")
+		}
+		s.ref = ref
+		c.pending = append(c.pending, s)
+	}
+	return nil
+}
+
+// Validate validates doc against the schema, and returns
+// the violations in document order.
+func (s *Schema) Validate(doc interface{}) []Violation {
+	v := &validator{}
+	v.validate(s, nil, doc)
+	return v.violations
+}
+
+type validator struct {
+	violations []Violation
+}
+
+func (v *validator) report(path []string, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{
+		Path: path,
+		Msg:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) validate(s *Schema, path []string, value interface{}) {
+	if s.always != nil {
+		if !*s.always {
+			v.report(path, "not allowed")
+		}
+		return
+	}
+	if s.resolved != nil {
+		v.validate(s.resolved, path, value)
+	}
+	if len(s.types) > 0 && !matchTypes(s.types, value) {
+		v.report(path, "expect %s, got %s", strings.Join(s.types, " or "), typeOf(value))
+		// other keywords are meaningless for a wrong type
+		return
+	}
+	if s.enum != nil && !inEnum(s.enum, value) {
+		v.report(path, "value %s is not one of %s", marshal(value), marshal(s.enum))
+	}
+	if s.hasConst && !ordered.DeepEqual(value, s.constant) {
+		v.report(path, "value %s is not %s", marshal(value), marshal(s.constant))
+	}
+	switch value := value.(type) {
+	case *ordered.Map:
+		v.validateObject(s, path, value)
+	case []interface{}:
+		v.validateArray(s, path, value)
+	case string:
+		v.validateString(s, path, value)
+	default:
+		if n, ok := toNumber(value); ok {
+			v.validateNumber(s, path, n)
+		}
+	}
+}
+
+func (v *validator) validateObject(s *Schema, path []string, m *ordered.Map) {
+	for _, name := range s.required {
+		if _, ok := m.Values[name]; !ok {
+			v.report(path, "missing required property %q", name)
+		}
+	}
+	for _, key := range m.Keys {
+		value := m.Values[key]
+		keyPath := pointer.Append(path, key)
+		matched := false
+		if p, ok := s.properties[key]; ok {
+			matched = true
+			v.validate(p, keyPath, value)
+		}
+		for _, pp := range s.patternProperties {
+			if pp.pattern.MatchString(key) {
+				matched = true
+				v.validate(pp.schema, keyPath, value)
+			}
+		}
+		if !matched && s.additionalProperties != nil {
+			if a := s.additionalProperties.always; a != nil && !*a {
+				v.report(keyPath, "additional property is not allowed")
+				continue
+			}
+			v.validate(s.additionalProperties, keyPath, value)
+		}
+	}
+}
+
+func (v *validator) validateArray(s *Schema, path []string, a []interface{}) {
+	if s.minItems != nil && len(a) < *s.minItems {
+		v.report(path, "expect at least %d items, got %d", *s.minItems, len(a))
+	}
+	if s.maxItems != nil && len(a) > *s.maxItems {
+		v.report(path, "expect at most %d items, got %d", *s.maxItems, len(a))
+	}
+	if s.items != nil {
+		for i, e := range a {
+			v.validate(s.items, pointer.Append(path, strconv.Itoa(i)), e)
+		}
+	}
+}
+
+func (v *validator) validateString(s *Schema, path []string, str string) {
+	n := utf8.RuneCountInString(str)
+	if s.minLength != nil && n < *s.minLength {
+		v.report(path, "expect at least %d characters, got %d", *s.minLength, n)
+	}
+	if s.maxLength != nil && n > *s.maxLength {
+		v.report(path, "expect at most %d characters, got %d", *s.maxLength, n)
+	}
+	if s.pattern != nil && !s.pattern.MatchString(str) {
+		v.report(path, "%q does not match pattern %q", str, s.pattern)
+	}
+}
+
+func (v *validator) validateNumber(s *Schema, path []string, n number) {
+	if s.minimum != nil && n.cmp(*s.minimum) < 0 {
+		v.report(path, "expect minimum %v, got %v", *s.minimum, n)
+	}
+	if s.maximum != nil && n.cmp(*s.maximum) > 0 {
+		v.report(path, "expect maximum %v, got %v", *s.maximum, n)
+	}
+	if s.exclusiveMinimum != nil && n.cmp(*s.exclusiveMinimum) <= 0 {
+		v.report(path, "expect greater than %v, got %v", *s.exclusiveMinimum, n)
+	}
+	if s.exclusiveMaximum != nil && n.cmp(*s.exclusiveMaximum) >= 0 {
+		v.report(path, "expect less than %v, got %v", *s.exclusiveMaximum, n)
+	}
+}
+
+// matchTypes tells if value is of one of the JSON Schema types
+func matchTypes(types []string, value interface{}) bool {
+	t := typeOf(value)
+	for _, want := range types {
+		if want == t || (want == "number" && t == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns the JSON Schema type of value, where numbers
+// without fractions are "integer".
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case *ordered.Map:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+	}
+	if n, ok := ordered.ToFloat(value); ok {
+		if n == math.Trunc(n) && !math.IsInf(n, 0) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, e := range enum {
+		if ordered.DeepEqual(value, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// toOrdered converts the maps in value of the schema to *ordered.Map,
+// to compare with values of documents.
+func toOrdered(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		m := ordered.New()
+		for k, e := range v {
+			m.Set(k, toOrdered(e))
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = toOrdered(e)
+		}
+		return s
+	default:
+		return v
+	}
+}
+
+// number is a JSON number, which keeps integers exactly
+type number struct {
+	f float64
+	// i is the exact value if the number is an integer
+	i *big.Int
+	// s is the representation of the number
+	s string
+}
+
+// toNumber converts v to number if it's a number
+func toNumber(v interface{}) (number, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil && !errors.Is(err, strconv.ErrRange) {
+			return number{}, false
+		}
+		n := number{f: f, s: v.String()}
+		if i, ok := new(big.Int).SetString(v.String(), 10); ok {
+			n.i = i
+		} else {
+			// e.g. 1.0 or 1e3
+			n.i = floatInt(f)
+		}
+		return n, true
+	default:
+		f, ok := ordered.ToFloat(v)
+		if !ok {
+			return number{}, false
+		}
+		return number{f: f, i: floatInt(f), s: fmt.Sprint(v)}, true
+	}
+}
+
+// floatInt returns f as an integer, or nil if it has fractions
+func floatInt(f float64) *big.Int {
+	if f != math.Trunc(f) || math.IsInf(f, 0) {
+		return nil
+	}
+	i, _ := big.NewFloat(f).Int(nil)
+	return i
+}
+
+// cmp compares n and m, exactly if both are integers
+func (n number) cmp(m number) int {
+	if n.i != nil && m.i != nil {
+		return n.i.Cmp(m.i)
+	}
+	switch {
+	case n.f < m.f:
+		return -1
+	case n.f > m.f:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// String implements fmt.Stringer.
+func (n number) String() string {
+	return n.s
+}
+
+func marshal(v interface{}) string {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(bs)
+}
+
+func stringList(v interface{}) ([]string, error) {
+	switch v := v.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		r := make([]string, 0, len(v))
+		for _, e := range v {
+			s, ok := e.(string)
+			if !ok {
+				return nil, fmt.Errorf("expects strings, got %v", e)
+			}
+			r = append(r, s)
+		}
+		return r, nil
+	default:
+		return nil, fmt.Errorf("expects a string or an array of strings, got %v", v)
+	}
+}
+
+// lookup returns the value at path of v, which is decoded into
+// map[string]interface{} and []interface{}.
+func lookup(v interface{}, path []string) (interface{}, bool) {
+	for _, token := range path {
+		switch n := v.(type) {
+		case map[string]interface{}:
+			child, ok := n[token]
+			if !ok {
+				return nil, false
+			}
+			v = child
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(n) {
+				return nil, false
+			}
+			v = n[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+func errorAt(path []string, format string, args ...interface{}) error {
+	return fmt.Errorf("invalid schema at %s: %s", pointer.Format(path), fmt.Sprintf(format, args...))
+}
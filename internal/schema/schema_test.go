@@ -0,0 +1,188 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/qjebbs/go-jsons/internal/ordered"
+	"github.com/qjebbs/go-jsons/internal/pointer"
+	"github.com/qjebbs/go-jsons/internal/schema"
+)
+
+const testSchema = `{
+	"type": "object",
+	"required": ["log", "inbounds"],
+	"properties": {
+		"log": {
+			"type": "object",
+			"properties": {
+				"level": {"enum": ["debug", "info", "error"]}
+			},
+			"additionalProperties": false
+		},
+		"inbounds": {
+			"type": "array",
+			"minItems": 1,
+			"items": {"$ref": "#/$defs/inbound"}
+		},
+		"tags": {"type": "array", "maxItems": 1, "items": {"type": "string", "pattern": "^[a-z]+$", "maxLength": 3}},
+		"version": {"const": {"major": 1}}
+	},
+	"patternProperties": {
+		"^x-": {"type": ["string", "null"]}
+	},
+	"$defs": {
+		"inbound": {
+			"type": "object",
+			"required": ["port"],
+			"properties": {
+				"port": {"type": "integer", "minimum": 1, "exclusiveMaximum": 65536},
+				"tag": {"type": "string", "minLength": 1}
+			}
+		}
+	}
+}`
+
+func TestValidate(t *testing.T) {
+	s, err := schema.Compile([]byte(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		name string
+		doc  string
+		want []string
+	}{
+		{
+			name: "valid",
+			doc:  `{"log":{"level":"info"},"inbounds":[{"port":1080,"tag":"in"}],"tags":["abc"],"x-a":null,"version":{"major":1}}`,
+		},
+		{
+			name: "missing required",
+			doc:  `{"inbounds":[{"tag":"in"}]}`,
+			want: []string{
+				`: missing required property "log"`,
+				`/inbounds/0: missing required property "port"`,
+			},
+		},
+		{
+			name: "every violation",
+			doc:  `{"log":{"level":"trace","file":"a"},"inbounds":[{"port":1.5},{"port":0,"tag":""},{"port":65536}],"tags":["ABC","abcd"],"x-b":1,"version":{"major":2}}`,
+			want: []string{
+				`/log/level: value "trace" is not one of ["debug","info","error"]`,
+				`/log/file: additional property is not allowed`,
+				`/inbounds/0/port: expect integer, got number`,
+				`/inbounds/1/port: expect minimum 1, got 0`,
+				`/inbounds/1/tag: expect at least 1 characters, got 0`,
+				`/inbounds/2/port: expect less than 65536, got 65536`,
+				`/tags: expect at most 1 items, got 2`,
+				`/tags/0: "ABC" does not match pattern "^[a-z]+$"`,
+				`/tags/1: expect at most 3 characters, got 4`,
+				`/x-b: expect string or null, got integer`,
+				`/version: value {"major":2} is not {"major":1}`,
+			},
+		},
+		{
+			name: "type",
+			doc:  `{"log":[],"inbounds":[]}`,
+			want: []string{
+				`/log: expect object, got array`,
+				`/inbounds: expect at least 1 items, got 0`,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			doc := ordered.New()
+			if err := json.Unmarshal([]byte(tc.doc), doc); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, v := range s.Validate(doc) {
+				got = append(got, pointer.Format(v.Path)+": "+v.Msg)
+			}
+			if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+				t.Errorf("want:\n%s\ngot:\n%s", strings.Join(tc.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestValidateNumber(t *testing.T) {
+	s, err := schema.Compile([]byte(`{"properties":{"id":{"type":"integer","maximum":10}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := ordered.New()
+	if err := doc.UnmarshalJSONUseNumber([]byte(`{"id":9007199254740993}`)); err != nil {
+		t.Fatal(err)
+	}
+	got := s.Validate(doc)
+	if len(got) != 1 || !strings.Contains(got[0].Msg, "maximum") {
+		t.Errorf("want a violation of maximum, got %v", got)
+	}
+	// integers beyond float64 precision are compared exactly
+	s, err = schema.Compile([]byte(`{"properties":{
+		"a":{"maximum":9007199254740992},
+		"b":{"minimum":9007199254740993},
+		"c":{"exclusiveMaximum":9007199254740993},
+		"d":{"minimum":1.5}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc = ordered.New()
+	if err := doc.UnmarshalJSONUseNumber([]byte(`{"a":9007199254740993,"b":9007199254740992,"c":9007199254740992,"d":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	var msgs []string
+	for _, v := range s.Validate(doc) {
+		msgs = append(msgs, pointer.Format(v.Path)+": "+v.Msg)
+	}
+	want := []string{
+		"/a: expect maximum 9007199254740992, got 9007199254740993",
+		"/b: expect minimum 9007199254740993, got 9007199254740992",
+		"/d: expect minimum 1.5, got 1",
+	}
+	if strings.Join(msgs, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(msgs, "\n"))
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, s := range []string{
+		`[]`,
+		`{"type":1}`,
+		`{"pattern":"("}`,
+		`{"minItems":-1}`,
+		`{"minimum":"1"}`,
+		`{"$ref":"#/$defs/none"}`,
+		`{"$ref":"other.json#/a"}`,
+		`{"$ref":"#"}`,
+		`{"$defs":{"a":{"$ref":"#/$defs/b"},"b":{"$ref":"#/$defs/a"}}}`,
+		`{"$defs":{"a":{"type":1}}}`,
+		`{"properties":{"a":3}}`,
+		`{"minItems":1.5}`,
+		`{} {}`,
+	} {
+		if _, err := schema.Compile([]byte(s)); err == nil {
+			t.Errorf("%s: want error, got nil", s)
+		}
+	}
+}
+
+func TestRecursiveRef(t *testing.T) {
+	s, err := schema.Compile([]byte(`{"$ref":"#/$defs/node","$defs":{"node":{"type":"object","properties":{"child":{"$ref":"#/$defs/node"},"v":{"type":"string"}}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := ordered.New()
+	if err := json.Unmarshal([]byte(`{"child":{"child":{"v":1}}}`), doc); err != nil {
+		t.Fatal(err)
+	}
+	got := s.Validate(doc)
+	if len(got) != 1 || pointer.Format(got[0].Path) != "/child/child/v" {
+		t.Errorf("want violation at /child/child/v, got %v", got)
+	}
+}
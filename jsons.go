@@ -9,12 +9,15 @@ package jsons
 //
 // Accepted Input:
 //
-//   - string: path to a local file
-//   - []string: paths of local files
+//   - string: path to a local file, directory, or glob pattern
+//   - []string: paths of local files, directories, or glob patterns
 //   - []byte: content of a file
 //   - [][]byte: content list of files
 //   - io.Reader: content reader
 //   - []io.Reader: content readers
+//   - FSPath, []FSPath: paths of files, directories, or glob patterns in fs.FS
+//   - *OrderedMap: a loaded document
+//   - *Overrides: values set at paths, from ParseOverrides or EnvOverrides
 //
 // If you need complex merging, create a custom merger with options.
 func Merge(inputs ...interface{}) ([]byte, error) {
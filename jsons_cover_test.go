@@ -245,7 +245,7 @@ func TestLoadNilInput(t *testing.T) {
 
 func TestGetExtensionsError(t *testing.T) {
 	m := jsons.NewMerger()
-	m.RegisterLoader("a", []string{".a1", ".a2"}, nil)
+	m.RegisterLoader("a", []string{".a1", ".a2"}, nopLoader)
 	_, err := m.Extensions("b")
 	if err == nil {
 		t.Error("want error, got nil")
@@ -285,26 +285,102 @@ func TestRegisterLoader(t *testing.T) {
 
 func TestRegisterLoaderError(t *testing.T) {
 	m := jsons.NewMerger()
-	err := m.RegisterLoader("a", []string{".a1", ".a2"}, nil)
+	err := m.RegisterLoader("a", []string{".a1", ".a2"}, nopLoader)
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = m.RegisterLoader("a", []string{".a1", ".a3"}, nil)
+	err = m.RegisterLoader("a", []string{".a1", ".a3"}, nopLoader)
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = m.RegisterLoader("b", []string{".a2"}, nil)
+	err = m.RegisterLoader("b", []string{".a2"}, nopLoader)
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = m.RegisterLoader("b", []string{".a1"}, nil)
+	err = m.RegisterLoader("b", []string{".a1"}, nopLoader)
 	if err == nil {
 		t.Error("want error, got nil")
 	}
-	err = m.RegisterLoader(jsons.FormatAuto, []string{".a1"}, nil)
+	err = m.RegisterLoader(jsons.FormatAuto, []string{".a1"}, nopLoader)
 	if err == nil {
 		t.Error("want error, got nil")
 	}
+	err = m.RegisterLoader("c", []string{".c"}, nil)
+	if err == nil {
+		t.Error("want error, got nil")
+	}
+	err = m.RegisterOrderedLoader("c", []string{".c"}, nil)
+	if err == nil {
+		t.Error("want error, got nil")
+	}
+}
+
+func TestLoaderDetection(t *testing.T) {
+	newLoader := func(name string) jsons.LoadOrderedFunc {
+		return func(b []byte) (*jsons.OrderedMap, error) {
+			m := jsons.NewOrderedMap()
+			m.Set("loader", name)
+			return m, nil
+		}
+	}
+	m := jsons.NewMerger()
+	// accepts anything, tried after the detected json loader
+	err := m.RegisterOrderedLoader("a", nil, newLoader("a"), jsons.WithLoaderPriority(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.RegisterOrderedLoader("b", nil, newLoader("b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.RegisterOrderedLoader("c", nil, newLoader("c"), jsons.WithLoaderDetect(func(b []byte) bool {
+		return strings.HasPrefix(string(b), "c:")
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		input string
+		want  string
+	}{
+		{input: `{"loader":"json"}`, want: "json"},
+		{input: `c: 1`, want: "c"},
+		{input: `b: 1`, want: "a"},
+	}
+	for _, tc := range testCases {
+		for i := 0; i < 10; i++ {
+			got, err := m.Merge([]byte(tc.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertJSONEqual(t, []byte(`{"loader":"`+tc.want+`"}`), got)
+		}
+	}
+}
+
+func TestLoaderDetectionErrorOrder(t *testing.T) {
+	m := jsons.NewMerger()
+	for _, name := range []jsons.Format{"d", "c", "b", "a"} {
+		err := m.RegisterOrderedLoader(name, nil, func(b []byte) (*jsons.OrderedMap, error) {
+			return nil, errors.New("failed")
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := m.Merge([]byte(`x`))
+	if err == nil {
+		t.Fatal("want error, got nil")
+	}
+	want := "inputs[0]: tried all formats but failed: " +
+		"[a] failed; [b] failed; [c] failed; [d] failed; " +
+		"[json] format not detected; [jsonc] format not detected; [jsonpatch] format not detected"
+	for i := 0; i < 10; i++ {
+		_, err := m.Merge([]byte(`x`))
+		if err.Error() != want {
+			t.Fatalf("want %q, got %q", want, err)
+		}
+	}
 }
 
 type errReader struct{}
@@ -159,3 +159,33 @@ func ExampleWithTypeOverride() {
 	fmt.Println(string(got))
 	// Output: {"a":false}
 }
+
+func ExampleWithMergePatchSemantics() {
+	a := []byte(`{"log":{"level":"debug","output":"stdout"},"dns":["1.1.1.1"]}`)
+	b := []byte(`{"log":{"output":null},"dns":["8.8.8.8"]}`)
+
+	m := jsons.NewMerger(
+		jsons.WithMergePatchSemantics(),
+	)
+	got, err := m.Merge(a, b)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(string(got))
+	// Output: {"log":{"level":"debug"},"dns":["8.8.8.8"]}
+}
+
+func ExampleWithDirectives() {
+	a := []byte(`{"dns":{"servers":["1.1.1.1"]},"log":{"level":"debug"},"rules":[{"outbound":"a"}]}`)
+	b := []byte(`{"dns":{"$replace":true,"servers":["8.8.8.8"]},"log":{"$delete":true},"rules":{"$prepend":[{"outbound":"b"}]}}`)
+
+	m := jsons.NewMerger(
+		jsons.WithDirectives(jsons.DefaultDirectivePrefix),
+	)
+	got, err := m.Merge(a, b)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(string(got))
+	// Output: {"dns":{"servers":["8.8.8.8"]},"rules":[{"outbound":"b"},{"outbound":"a"}]}
+}
@@ -2,6 +2,7 @@ package jsons_test
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"os"
 	"reflect"
@@ -194,3 +195,393 @@ func assertJSONEqual(t *testing.T, want, got []byte) {
 		t.Errorf("want:\n%s\n\ngot:\n%s", want, got)
 	}
 }
+
+// nopLoader loads any content to an empty map
+func nopLoader(b []byte) (map[string]interface{}, error) {
+	return map[string]interface{}{}, nil
+}
+
+func TestMergeJSONPatch(t *testing.T) {
+	a := []byte(`{"log":{"level":"debug"},"dns":["1.1.1.1","8.8.8.8"]}`)
+	b := []byte(`[
+		{"op":"test","path":"/log/level","value":"debug"},
+		{"op":"replace","path":"/log/level","value":"error"},
+		{"op":"remove","path":"/dns/0"},
+		{"op":"add","path":"/dns/-","value":"9.9.9.9"}
+	]`)
+	c := []byte(`{"dns":["1.0.0.1"]}`)
+	want := []byte(`{"log":{"level":"error"},"dns":["8.8.8.8","9.9.9.9","1.0.0.1"]}`)
+	got, err := jsons.Merge(a, b, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONEqual(t, want, got)
+
+	m := jsons.NewMerger()
+	got, err = m.MergeAs(jsons.FormatJSONPatch, []byte(`[{"op":"add","path":"/a","value":1}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONEqual(t, []byte(`{"a":1}`), got)
+}
+
+func TestMergeJSONPatchTestFailed(t *testing.T) {
+	a := []byte(`{"a":1}`)
+	b := []byte(`[{"op":"test","path":"/a","value":2}]`)
+	_, err := jsons.Merge(a, b)
+	if !errors.Is(err, jsons.ErrPatchTestFailed) {
+		t.Fatalf("want %v, got %v", jsons.ErrPatchTestFailed, err)
+	}
+	var perr *jsons.PatchError
+	if !errors.As(err, &perr) {
+		t.Fatalf("want *jsons.PatchError, got %T", err)
+	}
+	if perr.Index != 0 || perr.Op != "test" || perr.Path != "/a" {
+		t.Errorf("unexpected error: %v", perr)
+	}
+}
+
+func TestMergeArrayStrategy(t *testing.T) {
+	a := []byte(`{
+		"route": {"rules": [{"outbound": "a"}]},
+		"inbounds": [{"settings": {"clients": ["a"]}}],
+		"outbounds": [{"tag": "a"}]
+	}`)
+	b := []byte(`{
+		"route": {"rules": [{"outbound": "b"}]},
+		"inbounds": [{"settings": {"clients": ["a", "b"]}}],
+		"outbounds": [{"tag": "b"}]
+	}`)
+	want := []byte(`{
+		"route": {"rules": [{"outbound": "b"}]},
+		"inbounds": [{"settings": {"clients": ["a", "b"]}}],
+		"outbounds": [{"tag": "b"}, {"tag": "a"}]
+	}`)
+	m := jsons.NewMerger(
+		jsons.WithArrayStrategy("/route/rules", jsons.ArrayReplace),
+		jsons.WithArrayStrategy("/inbounds", jsons.ArrayMergeByIndex),
+		jsons.WithArrayStrategy("/inbounds/*/settings/clients", jsons.ArrayUnion),
+		jsons.WithArrayStrategy("/outbounds", jsons.ArrayPrepend),
+	)
+	got, err := m.Merge(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONEqual(t, want, got)
+}
+
+func TestMergeArrayStrategyInvalid(t *testing.T) {
+	for _, opt := range []jsons.Option{
+		jsons.WithArrayStrategy("route", jsons.ArrayReplace),
+		jsons.WithArrayStrategy("/route", "unknown"),
+	} {
+		m := jsons.NewMerger(opt)
+		_, err := m.Merge([]byte(`{}`))
+		if err == nil {
+			t.Error("want error, got nil")
+		}
+		_, err = m.MergeAs(jsons.FormatJSON, []byte(`{}`))
+		if err == nil {
+			t.Error("want error, got nil")
+		}
+	}
+}
+
+func TestMergeArrayStrategyWithMergeBy(t *testing.T) {
+	a := []byte(`{"outbounds": [{"tag": "a", "servers": [1]}]}`)
+	b := []byte(`{"outbounds": [{"tag": "a", "servers": [2]}]}`)
+	want := []byte(`{"outbounds": [{"tag": "a", "servers": [2]}]}`)
+	m := jsons.NewMerger(
+		jsons.WithMergeBy("tag"),
+		jsons.WithArrayStrategy("/outbounds/*/servers", jsons.ArrayReplace),
+	)
+	got, err := m.Merge(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONEqual(t, want, got)
+}
+
+func TestMergeDirectivesPrefix(t *testing.T) {
+	a := []byte(`{"a":[1],"b":{"$delete":true},"c":1}`)
+	b := []byte(`{"a":{"@replace":[2]},"c":{"@delete":true}}`)
+	want := []byte(`{"a":[2],"b":{"$delete":true}}`)
+	m := jsons.NewMerger(
+		jsons.WithDirectives("@"),
+	)
+	got, err := m.Merge(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONEqual(t, want, got)
+}
+
+func TestMergeJSONC(t *testing.T) {
+	f, err := os.CreateTemp("", "jsons-test-*.jsonc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`{
+  // inbounds
+  "inbounds": [
+    {"tag": "in-1"}, /* trailing comma */
+  ],
+}`)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := jsons.Merge(
+		f.Name(),
+		[]byte("// outbounds\n{\"outbounds\":[{\"tag\":\"out-1\"},],\"log\":{},}"),
+		[]byte(`{"log":{"level":"info"}}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// key order is kept
+	want := `{"inbounds":[{"tag":"in-1"}],"outbounds":[{"tag":"out-1"}],"log":{"level":"info"}}`
+	if string(got) != want {
+		t.Errorf("want:\n%s\n\ngot:\n%s", want, got)
+	}
+}
+
+func TestMergeInterpolation(t *testing.T) {
+	env := map[string]string{"LOG_LEVEL": "debug"}
+	m := jsons.NewMerger(jsons.WithInterpolation(func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}))
+	got, err := m.Merge(
+		[]byte(`{"log":{"level":"${LOG_LEVEL:-info}"},"inbounds":[{"port":1080}]}`),
+		[]byte(`{"api":{"port":"${/inbounds/0/port}","url":"http://localhost:${/inbounds/0/port}"}}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"log":{"level":"debug"},"inbounds":[{"port":1080}],"api":{"port":1080,"url":"http://localhost:1080"}}`
+	if string(got) != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+	// disabled by default
+	got, err = jsons.Merge([]byte(`{"a":"${A}"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"a":"${A}"}`; string(got) != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
+
+func TestMergeInterpolationEnv(t *testing.T) {
+	t.Setenv("JSONS_TEST_LEVEL", "error")
+	got, err := jsons.NewMerger(jsons.WithInterpolation(nil)).Merge([]byte(`{"level":"${JSONS_TEST_LEVEL}"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"level":"error"}`; string(got) != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
+
+func TestMergeUseNumber(t *testing.T) {
+	a := []byte(`{"id":9007199254740993,"ts":1700000000123456789,"list":[{"tag":"b","order":2},{"tag":"a","order":1.5}]}`)
+	b := []byte(`/* jsonc */ {"list":[{"tag":"a","value":12345678901234567890}]}`)
+	layer, err := jsons.ParseOverrides("ts=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := jsons.NewMerger(jsons.WithUseNumber(), jsons.WithMergeBy("tag"), jsons.WithOrderBy("order"))
+	got, err := m.Merge(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"id":9007199254740993,"ts":1700000000123456789,"list":[{"tag":"a","value":12345678901234567890,"order":1.5},{"tag":"b","order":2}]}`
+	if string(got) != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+	// json.Number and float64 are of the same type
+	got, err = m.Merge(a, layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), `"ts":1,`) {
+		t.Errorf("want ts overridden, got %s", got)
+	}
+	// patches and overrides keep the precision too
+	p := []byte(`[{"op":"replace","path":"/id","value":9007199254740995}]`)
+	layer, err = jsons.ParseOverrides("ts=1700000000123456788", "extra=[12345678901234567891]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = m.Merge(a, p, layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `{"id":9007199254740995,"ts":1700000000123456788,"list":[{"tag":"a","order":1.5},{"tag":"b","order":2}],"extra":[12345678901234567891]}`
+	if string(got) != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+	// integers are validated exactly against the schema
+	s := jsons.NewMerger(jsons.WithUseNumber(), jsons.WithSchema([]byte(`{"properties":{"id":{"maximum":9007199254740992}}}`)))
+	_, err = s.Merge(a)
+	if !errors.Is(err, jsons.ErrSchemaViolation) {
+		t.Errorf("want %v, got %v", jsons.ErrSchemaViolation, err)
+	}
+	// float64 by default
+	got, err = jsons.Merge(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(got), "9007199254740993") {
+		t.Errorf("want float64 numbers by default, got %s", got)
+	}
+}
+
+func TestMergeByNestedAndComposite(t *testing.T) {
+	testCases := []struct {
+		name    string
+		options []jsons.Option
+		a, b    string
+		want    string
+	}{
+		{
+			name:    "nested",
+			options: []jsons.Option{jsons.WithMergeBy("settings.id")},
+			a:       `{"list":[{"settings":{"id":1},"v":1},{"settings":{"id":2},"v":2}]}`,
+			b:       `{"list":[{"settings":{"id":2},"v":3}]}`,
+			want:    `{"list":[{"settings":{"id":1},"v":1},{"settings":{"id":2},"v":3}]}`,
+		},
+		{
+			name:    "literal key first",
+			options: []jsons.Option{jsons.WithMergeBy("a.b")},
+			a:       `{"list":[{"a.b":"x","a":{"b":"y"},"v":1}]}`,
+			b:       `{"list":[{"a.b":"y","v":2},{"a":{"b":"x"},"v":3}]}`,
+			// the literal "x" of the first is used, not the nested "y"
+			want: `{"list":[{"a.b":"x","a":{"b":"x"},"v":3},{"a.b":"y","v":2}]}`,
+		},
+		{
+			name:    "nested and remove",
+			options: []jsons.Option{jsons.WithMergeByAndRemove("settings._id")},
+			a:       `{"list":[{"settings":{"_id":"a","x":1}}]}`,
+			b:       `{"list":[{"settings":{"_id":"a","y":2}}]}`,
+			want:    `{"list":[{"settings":{"x":1,"y":2}}]}`,
+		},
+		{
+			name:    "composite",
+			options: []jsons.Option{jsons.WithMergeByKeys("protocol", "port")},
+			a:       `{"list":[{"protocol":"tcp","port":443,"v":1},{"protocol":"udp","port":443,"v":2}]}`,
+			b:       `{"list":[{"protocol":"udp","port":443,"v":3},{"protocol":"tcp","v":4}]}`,
+			want:    `{"list":[{"protocol":"tcp","port":443,"v":1},{"protocol":"udp","port":443,"v":3},{"protocol":"tcp","v":4}]}`,
+		},
+		{
+			name:    "composite nested",
+			options: []jsons.Option{jsons.WithMergeByKeys("protocol", "settings.port")},
+			a:       `{"list":[{"protocol":"tcp","settings":{"port":443},"v":1}]}`,
+			b:       `{"list":[{"protocol":"tcp","settings":{"port":443},"v":2},{"protocol":"tcp","settings":{"port":"443"},"v":3}]}`,
+			want:    `{"list":[{"protocol":"tcp","settings":{"port":443},"v":2},{"protocol":"tcp","settings":{"port":"443"},"v":3}]}`,
+		},
+		{
+			name:    "numbers with UseNumber",
+			options: []jsons.Option{jsons.WithMergeBy("id"), jsons.WithUseNumber()},
+			a:       `{"list":[{"id":9007199254740993,"v":1},{"id":9007199254740992,"v":2}]}`,
+			b:       `{"list":[{"id":9007199254740993,"v":3}]}`,
+			want:    `{"list":[{"id":9007199254740993,"v":3},{"id":9007199254740992,"v":2}]}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := jsons.NewMerger(tc.options...).Merge([]byte(tc.a), []byte(tc.b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("want:\n%s\ngot:\n%s", tc.want, got)
+			}
+		})
+	}
+	if _, err := jsons.NewMerger(jsons.WithMergeByKeys()).Merge([]byte(`{}`)); err == nil {
+		t.Error("want error of no keys")
+	}
+}
+
+func TestMergeByOrderByAt(t *testing.T) {
+	a := []byte(`{
+		"outbounds":[{"tag":"a","v":1}],
+		"inbounds":[{"tag":"x","v":1,"_order":2}],
+		"route":{"rules":[{"priority":2,"id":"r2"},{"priority":1,"id":"r1"}]},
+		"log":{"priority":"high"}
+	}`)
+	b := []byte(`{
+		"outbounds":[{"tag":"a","v":2}],
+		"inbounds":[{"tag":"x","v":2,"_order":1}]
+	}`)
+	m := jsons.NewMerger(
+		jsons.WithMergeByAt("/outbounds", "tag"),
+		jsons.WithOrderByAtAndRemove("/route/rules", "priority"),
+		jsons.WithOrderByAt("/inbounds", "_order"),
+	)
+	got, err := m.Merge(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"outbounds":[{"tag":"a","v":2}],` +
+		`"inbounds":[{"tag":"x","v":2,"_order":1},{"tag":"x","v":1,"_order":2}],` +
+		`"route":{"rules":[{"id":"r1"},{"id":"r2"}]},` +
+		`"log":{"priority":"high"}}`
+	if string(got) != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+
+	// helper fields are removed only from the elements of scoped arrays
+	m = jsons.NewMerger(jsons.WithMergeByAtAndRemove("/*/list", "_id"))
+	got, err = m.Merge(
+		[]byte(`{"a":{"list":[{"_id":"x","v":1}]},"b":{"_id":"kept","list":[{"v":0,"sub":[{"_id":"kept"}]}]}}`),
+		[]byte(`{"a":{"list":[{"_id":"x","v":2}]}}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `{"a":{"list":[{"v":2}]},"b":{"_id":"kept","list":[{"v":0,"sub":[{"_id":"kept"}]}]}}`
+	if string(got) != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+
+	// composite keys at path
+	m = jsons.NewMerger(jsons.WithMergeByKeysAt("/inbounds", "protocol", "port"))
+	got, err = m.Merge(
+		[]byte(`{"inbounds":[{"protocol":"tcp","port":1,"v":1}],"other":[{"protocol":"tcp","port":1,"v":1}]}`),
+		[]byte(`{"inbounds":[{"protocol":"tcp","port":1,"v":2}],"other":[{"protocol":"tcp","port":1,"v":2}]}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `{"inbounds":[{"protocol":"tcp","port":1,"v":2}],"other":[{"protocol":"tcp","port":1,"v":1},{"protocol":"tcp","port":1,"v":2}]}`
+	if string(got) != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+
+	// errors of scoped arrays
+	m = jsons.NewMerger(jsons.WithMergeByAt("/route/rules", "tag"), jsons.WithStrictConflicts())
+	for _, tc := range []struct {
+		b    string
+		want error
+	}{
+		{`{"route":{"rules":[{"tag":"x","v":"a"}]}}`, jsons.ErrTypeMismatch},
+		{`{"route":{"rules":[{"tag":"x","v":2}]}}`, jsons.ErrConflict},
+	} {
+		got, err = m.Merge([]byte(`{"route":{"rules":[{"tag":"x","v":1}]}}`), []byte(tc.b))
+		var me *jsons.MergeError
+		if !errors.Is(err, tc.want) || !errors.As(err, &me) {
+			t.Fatalf("want %v, got %v: %s", tc.want, err, got)
+		}
+		if me.Input != "inputs[1]" || me.Path != "/route/rules/0/v" {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	if _, err := jsons.NewMerger(jsons.WithMergeByAt("no-slash", "tag")).Merge([]byte(`{}`)); err == nil {
+		t.Error("want error of invalid path")
+	}
+}
@@ -0,0 +1,160 @@
+package jsons
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/qjebbs/go-jsons/internal/ordered"
+	"github.com/qjebbs/go-jsons/internal/pointer"
+)
+
+var (
+	// ErrInputTooLarge is the error of inputs larger than the limit, see WithMaxInputSize
+	ErrInputTooLarge = errors.New("input too large")
+	// ErrTooDeep is the error of documents nested deeper than the limit, see WithMaxDepth
+	ErrTooDeep = ordered.ErrTooDeep
+	// ErrTooManyKeys is the error of inputs with more keys than the limit, see WithMaxKeys
+	ErrTooManyKeys = ordered.ErrTooManyKeys
+)
+
+// loadContext is the context of loading inputs in a merge call
+type loadContext struct {
+	ctx context.Context
+	// fsys is the file system of inputs without their own
+	fsys fileSystem
+	// maxSize is the max size of an input, 0 for unlimited
+	maxSize int64
+	// maxDepth and maxKeys are the limits checked while decoding,
+	// see WithMaxDepth and WithMaxKeys
+	maxDepth int
+	maxKeys  int
+	// keys is the number of keys loaded, see WithMaxKeys
+	keys int
+	// useNumber keeps numbers as json.Number, see WithUseNumber
+	useNumber bool
+}
+
+// readAll reads all of r named name, with the context and size limit
+func (c *loadContext) readAll(name string, r io.Reader) ([]byte, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, &MergeError{Input: name, Err: err}
+	}
+	r = &contextReader{ctx: c.ctx, r: r}
+	if c.maxSize > 0 {
+		// read one more byte to tell if it exceeds the limit
+		r = io.LimitReader(r, c.maxSize+1)
+	}
+	bs, err := io.ReadAll(r)
+	if err != nil {
+		return nil, &MergeError{Input: name, Err: err}
+	}
+	if err := c.checkSize(name, bs); err != nil {
+		return nil, err
+	}
+	return bs, nil
+}
+
+// readFile reads the file of fsys, with the context and size limit
+func (c *loadContext) readFile(fsys fileSystem, file string) ([]byte, error) {
+	f, err := fsys.Open(file)
+	if err != nil {
+		return nil, &MergeError{Input: file, Err: err}
+	}
+	defer f.Close()
+	return c.readAll(file, f)
+}
+
+// checkSize checks the size of the input b named name
+func (c *loadContext) checkSize(name string, b []byte) error {
+	if c.maxSize > 0 && int64(len(b)) > c.maxSize {
+		return &MergeError{Input: name, Err: fmt.Errorf("%w: exceeds %d bytes", ErrInputTooLarge, c.maxSize)}
+	}
+	return nil
+}
+
+// decodeOptions returns the options of decoding an input, where
+// the keys loaded by previous inputs count against the limit.
+func (c *loadContext) decodeOptions() ordered.DecodeOptions {
+	return ordered.DecodeOptions{
+		UseNumber: c.useNumber,
+		MaxDepth:  c.maxDepth,
+		MaxKeys:   c.maxKeys,
+		Keys:      c.keys,
+	}
+}
+
+// contextReader is a reader who stops reading once the context is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// checkLimits checks the depth and keys of the document against
+// the limits, where the keys are counted across the merge call.
+//
+// The built-in loaders check the limits while decoding, this checks
+// the documents of other loaders, and the values of patches at their
+// target paths.
+func (m *Merger) checkLimits(s *mergeState, doc *document) error {
+	if m.options.MaxDepth <= 0 && m.options.MaxKeys <= 0 {
+		return nil
+	}
+	if doc.Map != nil {
+		return m.checkValueLimits(s, doc.Source, nil, doc.Map)
+	}
+	for _, op := range doc.Patch {
+		if err := m.checkValueLimits(s, doc.Source, op.Path, op.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkValueLimits checks the value v at path of the input source
+func (m *Merger) checkValueLimits(s *mergeState, source string, path []string, v interface{}) error {
+	switch v.(type) {
+	case *ordered.Map, []interface{}:
+	default:
+		return nil
+	}
+	if limit := m.options.MaxDepth; limit > 0 && len(path) >= limit {
+		return &MergeError{
+			Input: source,
+			Path:  pointer.Format(path),
+			Err:   fmt.Errorf("%w: %d", ErrTooDeep, limit),
+		}
+	}
+	switch v := v.(type) {
+	case *ordered.Map:
+		s.load.keys += len(v.Keys)
+		if limit := m.options.MaxKeys; limit > 0 && s.load.keys > limit {
+			return &MergeError{
+				Input: source,
+				Path:  pointer.Format(path),
+				Err:   fmt.Errorf("%w: %d", ErrTooManyKeys, limit),
+			}
+		}
+		for _, k := range v.Keys {
+			if err := m.checkValueLimits(s, source, pointer.Append(path, k), v.Values[k]); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, e := range v {
+			if err := m.checkValueLimits(s, source, pointer.Append(path, strconv.Itoa(i)), e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package jsons_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/qjebbs/go-jsons"
+)
+
+func TestMergeContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := jsons.NewMerger().MergeContext(ctx, []byte(`{"a":1}`))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("want context.Canceled, got %v", err)
+	}
+}
+
+// cancelReader cancels the context once it's read
+type cancelReader struct {
+	cancel context.CancelFunc
+}
+
+func (r *cancelReader) Read(p []byte) (int, error) {
+	r.cancel()
+	return copy(p, `{"a":`), nil
+}
+
+func TestMergeContextCancelReading(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err := jsons.NewMerger().MergeContext(ctx, &cancelReader{cancel})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("want context.Canceled, got %v", err)
+	}
+}
+
+func TestMergeLimits(t *testing.T) {
+	testCases := []struct {
+		name   string
+		option jsons.Option
+		input  interface{}
+		want   error
+		path   string
+	}{
+		{
+			name:   "size of bytes",
+			option: jsons.WithMaxInputSize(8),
+			input:  []byte(`{"a":"123"}`),
+			want:   jsons.ErrInputTooLarge,
+		},
+		{
+			name:   "size of reader",
+			option: jsons.WithMaxInputSize(8),
+			input:  io.MultiReader(strings.NewReader(`{"a":"123"}`), endlessReader{}),
+			want:   jsons.ErrInputTooLarge,
+		},
+		{
+			name:   "size of file",
+			option: jsons.WithMaxInputSize(8),
+			input:  jsons.FSPath{FS: fstest.MapFS{"a.json": {Data: []byte(`{"a":"123"}`)}}, Path: "a.json"},
+			want:   jsons.ErrInputTooLarge,
+		},
+		{
+			name:   "size of unknown file",
+			option: jsons.WithMaxInputSize(8),
+			input:  jsons.FSPath{FS: fstest.MapFS{"a": {Data: []byte(`{"a":"123"}`)}}, Path: "a"},
+			want:   jsons.ErrInputTooLarge,
+		},
+		{
+			name:   "size ok",
+			option: jsons.WithMaxInputSize(11),
+			input:  []byte(`{"a":"123"}`),
+		},
+		{
+			name:   "depth",
+			option: jsons.WithMaxDepth(2),
+			input:  []byte(`{"a":{"b":[1]}}`),
+			want:   jsons.ErrTooDeep,
+			path:   "/a/b",
+		},
+		{
+			name:   "depth ok",
+			option: jsons.WithMaxDepth(3),
+			input:  []byte(`{"a":{"b":[1]}}`),
+		},
+		{
+			name:   "keys",
+			option: jsons.WithMaxKeys(2),
+			input:  []byte(`{"a":{"b":1,"c":2}}`),
+			want:   jsons.ErrTooManyKeys,
+			path:   "/a",
+		},
+		{
+			name:   "depth of patch value",
+			option: jsons.WithMaxDepth(2),
+			input:  []byte(`[{"op":"add","path":"/a","value":{"b":[1]}}]`),
+			want:   jsons.ErrTooDeep,
+			path:   "/a/b",
+		},
+		{
+			name:   "keys of patch value",
+			option: jsons.WithMaxKeys(1),
+			input:  []byte(`[{"op":"add","path":"/a","value":{"b":1,"c":2}}]`),
+			want:   jsons.ErrTooManyKeys,
+			path:   "/a",
+		},
+		{
+			name:   "keys across inputs",
+			option: jsons.WithMaxKeys(2),
+			input:  [][]byte{[]byte(`{"a":1}`), []byte(`{"a":2}`), []byte(`{"a":3}`)},
+			want:   jsons.ErrTooManyKeys,
+			path:   "",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := jsons.NewMerger(tc.option).MergeContext(context.Background(), tc.input)
+			if tc.want == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("want %v, got %v", tc.want, err)
+			}
+			var me *jsons.MergeError
+			if !errors.As(err, &me) {
+				t.Fatalf("want *MergeError, got %T", err)
+			}
+			if me.Path != tc.path {
+				t.Errorf("want path %q, got %q", tc.path, me.Path)
+			}
+		})
+	}
+}
+
+func TestMergeLimitsWhileDecoding(t *testing.T) {
+	// deeper than the decoder would recurse comfortably, but within
+	// the nesting limit of encoding/json which validates the syntax
+	const depth = 9000
+	deep := `{"a":` + strings.Repeat(`[`, depth) + strings.Repeat(`]`, depth) + `}`
+	testCases := []struct {
+		name  string
+		input interface{}
+	}{
+		{name: "json", input: strings.NewReader(deep)},
+		{name: "jsonc", input: jsons.FSPath{FS: fstest.MapFS{"a.jsonc": {Data: []byte("// deep\n" + deep)}}, Path: "a.jsonc"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := jsons.NewMerger(jsons.WithMaxDepth(3)).Merge(tc.input)
+			var me *jsons.MergeError
+			if !errors.As(err, &me) || !errors.Is(err, jsons.ErrTooDeep) {
+				t.Fatalf("want %v, got %v", jsons.ErrTooDeep, err)
+			}
+			if me.Path != "/a/0/0" || me.Column != 8 {
+				t.Errorf("want /a/0/0 at column 8, got %s at column %d", me.Path, me.Column)
+			}
+		})
+	}
+	// documents of custom loaders are checked after loading
+	m := jsons.NewMerger(jsons.WithMaxKeys(1))
+	err := m.RegisterOrderedLoader("custom", []string{".custom"}, func(b []byte) (*jsons.OrderedMap, error) {
+		o := jsons.NewOrderedMap()
+		o.Set("a", 1)
+		o.Set("b", 2)
+		return o, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.MergeAs("custom", []byte(`a,b`))
+	if !errors.Is(err, jsons.ErrTooManyKeys) {
+		t.Errorf("want %v, got %v", jsons.ErrTooManyKeys, err)
+	}
+}
+
+// endlessReader reads spaces endlessly
+type endlessReader struct{}
+
+func (endlessReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = ' '
+	}
+	return len(p), nil
+}
@@ -3,9 +3,9 @@ package jsons
 import (
 	"fmt"
 	"io"
-	"os"
 
 	"github.com/qjebbs/go-jsons/internal/ordered"
+	"github.com/qjebbs/go-jsons/internal/patch"
 )
 
 // OrderedMap is an alias of ordered.Map
@@ -14,17 +14,50 @@ type OrderedMap = ordered.Map
 // NewOrderedMap is an alias of ordered.New
 var NewOrderedMap = ordered.New
 
+// PatchError is an alias of patch.Error, the error of a failed JSON Patch operation
+type PatchError = patch.Error
+
+// ErrPatchTestFailed is the error of a failed JSON Patch "test" operation
+var ErrPatchTestFailed = patch.ErrTestFailed
+
 // LoadFunc load the input bytes to map[string]interface{}
 type LoadFunc func([]byte) (map[string]interface{}, error)
 
 // LoadOrderedFunc load the input bytes to *OrderedMap, which keeps the fields order
 type LoadOrderedFunc func([]byte) (*OrderedMap, error)
 
+// loadPatchFunc load the input bytes to a patch, which keeps
+// numbers as json.Number if useNumber
+type loadPatchFunc func(b []byte, useNumber bool) (patch.Patch, error)
+
+// decodeFunc decodes the input bytes to *OrderedMap with the options
+type decodeFunc func([]byte, ordered.DecodeOptions) (*OrderedMap, error)
+
 // loader is a configurable loader for specific format files.
 type loader struct {
-	Name       Format
-	Extensions []string
-	LoadFunc   LoadOrderedFunc
+	Name          Format
+	Extensions    []string
+	LoadFunc      LoadOrderedFunc
+	LoadPatchFunc loadPatchFunc
+	// DecodeFunc decodes with the limits and WithUseNumber of the
+	// merge, which replaces LoadFunc if not nil.
+	DecodeFunc decodeFunc
+	Priority   int
+	Detect     func([]byte) bool
+}
+
+// document is a loaded input, which is either a map to merge,
+// or a patch to apply to the merged map.
+type document struct {
+	Map       *OrderedMap
+	Patch     patch.Patch
+	Overrides *Overrides
+	// Source is the file path of the document, or the name
+	// of the input for others, see Provenance for details.
+	Source string
+	// File is the path of the document in FS, empty if it's not a file
+	File string
+	FS   fileSystem
 }
 
 // makeLoader makes a merger who merge the format by converting it to JSON
@@ -36,77 +69,150 @@ func newLoader(name Format, extensions []string, fn LoadOrderedFunc) *loader {
 	}
 }
 
-// makeLoadFunc makes a merge func who merge the input to
-func (l *loader) Load(input interface{}) ([]*OrderedMap, error) {
+// newPatchLoader makes a loader who loads patches
+func newPatchLoader(name Format, extensions []string, fn loadPatchFunc) *loader {
+	return &loader{
+		Name:          name,
+		Extensions:    extensions,
+		LoadPatchFunc: fn,
+	}
+}
+
+// Load loads documents from input with the context c, name is the name
+// of input, which names the documents if they are not files.
+func (l *loader) Load(c *loadContext, name string, input interface{}) ([]*document, error) {
 	if input == nil {
 		return nil, nil
 	}
 	switch v := input.(type) {
 	case string:
-		return l.loadFiles([]string{v})
+		return l.loadFiles(c, c.fsys, []string{v})
 	case []string:
-		return l.loadFiles(v)
+		return l.loadFiles(c, c.fsys, v)
+	case FSPath:
+		return l.loadFiles(c, fileSystemOf(v.FS, c.fsys), []string{v.Path})
+	case []FSPath:
+		var docs []*document
+		for _, p := range v {
+			d, err := l.loadFiles(c, fileSystemOf(p.FS, c.fsys), []string{p.Path})
+			if err != nil {
+				return nil, err
+			}
+			docs = append(docs, d...)
+		}
+		return docs, nil
+	case *OrderedMap:
+		return []*document{{Map: ordered.DeepCopy(v).(*OrderedMap), Source: name}}, nil
+	case *Overrides:
+		return []*document{{Overrides: v, Source: name}}, nil
 	case []byte:
-		return l.loadSlices([][]byte{v})
+		if err := c.checkSize(name, v); err != nil {
+			return nil, err
+		}
+		doc, err := l.load(c, name, v)
+		if err != nil {
+			return nil, err
+		}
+		return []*document{doc}, nil
 	case [][]byte:
-		return l.loadSlices(v)
+		return l.loadSlices(c, name, v)
 	case io.Reader:
-		return l.loadReaders([]io.Reader{v})
+		doc, err := l.loadReader(c, name, v)
+		if err != nil {
+			return nil, err
+		}
+		return []*document{doc}, nil
 	case []io.Reader:
-		return l.loadReaders(v)
+		return l.loadReaders(c, name, v)
 	default:
-		return nil, fmt.Errorf("unsupported input type: %T", input)
+		return nil, &MergeError{Input: name, Err: fmt.Errorf("unsupported input type: %T", input)}
 	}
 }
 
-func (l *loader) loadFiles(files []string) ([]*OrderedMap, error) {
-	maps := make([]*OrderedMap, 0, len(files))
+func (l *loader) loadFiles(c *loadContext, fsys fileSystem, files []string) ([]*document, error) {
+	docs := make([]*document, 0, len(files))
 	for _, file := range files {
-		m, err := l.loadFile(file)
+		doc, err := l.loadFile(c, fsys, file)
 		if err != nil {
 			return nil, err
 		}
-		maps = append(maps, m)
+		docs = append(docs, doc)
 	}
-	return maps, nil
+	return docs, nil
 }
 
-func (l *loader) loadReaders(readers []io.Reader) ([]*OrderedMap, error) {
-	maps := make([]*OrderedMap, 0, len(readers))
-	for _, r := range readers {
-		m, err := l.loadReader(r)
+func (l *loader) loadReaders(c *loadContext, name string, readers []io.Reader) ([]*document, error) {
+	docs := make([]*document, 0, len(readers))
+	for i, r := range readers {
+		doc, err := l.loadReader(c, fmt.Sprintf("%s[%d]", name, i), r)
 		if err != nil {
 			return nil, err
 		}
-		maps = append(maps, m)
+		docs = append(docs, doc)
 	}
-	return maps, nil
+	return docs, nil
 }
 
-func (l *loader) loadSlices(slices [][]byte) ([]*OrderedMap, error) {
-	maps := make([]*OrderedMap, 0, len(slices))
-	for _, slice := range slices {
-		m, err := l.LoadFunc(slice)
+func (l *loader) loadSlices(c *loadContext, name string, slices [][]byte) ([]*document, error) {
+	docs := make([]*document, 0, len(slices))
+	for i, slice := range slices {
+		name := fmt.Sprintf("%s[%d]", name, i)
+		if err := c.checkSize(name, slice); err != nil {
+			return nil, err
+		}
+		doc, err := l.load(c, name, slice)
 		if err != nil {
 			return nil, err
 		}
-		maps = append(maps, m)
+		docs = append(docs, doc)
 	}
-	return maps, nil
+	return docs, nil
 }
 
-func (l *loader) loadFile(file string) (*OrderedMap, error) {
-	bs, err := os.ReadFile(file)
+func (l *loader) loadFile(c *loadContext, fsys fileSystem, file string) (*document, error) {
+	bs, err := c.readFile(fsys, file)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := l.load(c, file, bs)
 	if err != nil {
 		return nil, err
 	}
-	return l.LoadFunc(bs)
+	doc.File, doc.FS = file, fsys
+	return doc, nil
 }
 
-func (l *loader) loadReader(reader io.Reader) (*OrderedMap, error) {
-	bs, err := io.ReadAll(reader)
+func (l *loader) loadReader(c *loadContext, name string, reader io.Reader) (*document, error) {
+	bs, err := c.readAll(name, reader)
 	if err != nil {
 		return nil, err
 	}
-	return l.LoadFunc(bs)
+	return l.load(c, name, bs)
+}
+
+// load loads the document named name from b with the context c
+func (l *loader) load(c *loadContext, name string, b []byte) (*document, error) {
+	doc := &document{Source: name}
+	if l.LoadPatchFunc != nil {
+		p, err := l.LoadPatchFunc(b, c.useNumber)
+		if err != nil {
+			return nil, newLoadError(name, b, err)
+		}
+		doc.Patch = p
+		return doc, nil
+	}
+	var (
+		m   *OrderedMap
+		err error
+	)
+	if l.DecodeFunc != nil {
+		m, err = l.DecodeFunc(b, c.decodeOptions())
+	} else {
+		m, err = l.LoadFunc(b)
+	}
+	if err != nil {
+		return nil, newLoadError(name, b, err)
+	}
+	doc.Map = m
+	return doc, nil
 }
@@ -1,20 +1,33 @@
 package jsons
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"path/filepath"
 	"strings"
+	"sync"
 
+	"github.com/qjebbs/go-jsons/internal/interpolate"
 	"github.com/qjebbs/go-jsons/internal/merge"
 	"github.com/qjebbs/go-jsons/internal/ordered"
+	"github.com/qjebbs/go-jsons/internal/patch"
 )
 
-// Merger is the json merger
+// Merger is the json merger, which is safe for concurrent use.
+//
+// The registered loaders and encoders are locked for reading during a
+// merge, so the loaders, encoders, visitors and other callbacks run by
+// the merge must not call RegisterLoader, RegisterOrderedLoader or
+// RegisterEncoder of the same Merger, which would deadlock.
 type Merger struct {
+	// mu guards the loaders and encoders, while
+	// options are immutable after construction
+	mu            sync.RWMutex
 	loadersByName map[Format]*loader
 	loadersByExt  map[string]*loader
+	encoders      map[Format]EncodeFunc
 	options       options
 }
 
@@ -23,6 +36,7 @@ func NewMerger(options ...Option) *Merger {
 	m := &Merger{
 		loadersByName: make(map[Format]*loader),
 		loadersByExt:  make(map[string]*loader),
+		encoders:      make(map[Format]EncodeFunc),
 	}
 	for _, opt := range options {
 		opt(m)
@@ -31,17 +45,69 @@ func NewMerger(options ...Option) *Merger {
 	_ = m.RegisterOrderedLoader(
 		FormatJSON,
 		[]string{".json"},
-		func(v []byte) (*ordered.Map, error) {
-			m := ordered.New()
-			if err := json.Unmarshal(v, m); err != nil {
-				return nil, err
-			}
-			return m, nil
-		},
+		loadJSON,
+		withLoaderDecode(decodeJSON),
+		WithLoaderDetect(func(b []byte) bool {
+			return startsWith(b, '{')
+		}),
+	)
+	_ = m.RegisterOrderedLoader(
+		FormatJSONC,
+		[]string{".jsonc"},
+		loadJSONC,
+		withLoaderDecode(decodeJSONC),
+		WithLoaderDetect(func(b []byte) bool {
+			return startsWith(b, '{', '/')
+		}),
+		// try standard JSON first
+		WithLoaderPriority(-1),
+	)
+	_ = m.registerLoader(
+		newPatchLoader(
+			FormatJSONPatch,
+			[]string{".jsonpatch"},
+			loadPatch,
+		),
+		WithLoaderDetect(func(b []byte) bool {
+			return startsWith(b, '[')
+		}),
 	)
 	return m
 }
 
+// Clone returns a copy of the merger, with the same options, loaders
+// and encoders, which can be modified without affecting the original.
+func (m *Merger) Clone() *Merger {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	c := &Merger{
+		loadersByName: make(map[Format]*loader, len(m.loadersByName)),
+		loadersByExt:  make(map[string]*loader, len(m.loadersByExt)),
+		encoders:      make(map[Format]EncodeFunc, len(m.encoders)),
+		options:       m.options.clone(),
+	}
+	for k, v := range m.loadersByName {
+		c.loadersByName[k] = v
+	}
+	for k, v := range m.loadersByExt {
+		c.loadersByExt[k] = v
+	}
+	for k, v := range m.encoders {
+		c.encoders[k] = v
+	}
+	return c
+}
+
+// With returns a copy of the merger with the options applied,
+// the original merger is not affected.
+func (m *Merger) With(options ...Option) *Merger {
+	c := m.Clone()
+	for _, opt := range options {
+		opt(c)
+	}
+	return c
+}
+
 // Merge merges inputs into a single json.
 //
 // It detects the format by file extension, or try all mergers
@@ -49,134 +115,330 @@ func NewMerger(options ...Option) *Merger {
 //
 // Accepted Input:
 //
-//   - string: path to a local file
-//   - []string: paths of local files
+//   - string: path to a local file, directory, or glob pattern
+//   - []string: paths of local files, directories, or glob patterns
 //   - []byte: content of a file
 //   - [][]byte: content list of files
 //   - io.Reader: content reader
 //   - []io.Reader: content readers
+//   - FSPath, []FSPath: paths of files, directories, or glob patterns in fs.FS
+//   - *OrderedMap: a loaded document
+//   - *Overrides: values set at paths, from ParseOverrides or EnvOverrides
 func (m *Merger) Merge(inputs ...interface{}) ([]byte, error) {
-	target := ordered.New()
-	for _, input := range inputs {
-		err := m.mergeToMap(input, target)
-		if err != nil {
-			return nil, err
-		}
-	}
-	err := m.options.apply(target)
-	if err != nil {
-		return nil, err
-	}
-	if m.options.MarshalIndent != "" {
-		return json.MarshalIndent(target, m.options.MarshalPrefix, m.options.MarshalIndent)
-	}
-	return json.Marshal(target)
+	return m.MergeAs(FormatAuto, inputs...)
 }
 
 // MergeAs loads inputs of the specific format and merges into a single json.
 //
 // Accepted Input:
 //
-//   - string: path to a local file
-//   - []string: paths of local files
+//   - string: path to a local file, directory, or glob pattern
+//   - []string: paths of local files, directories, or glob patterns
 //   - []byte: content of a file
 //   - [][]byte: content list of files
 //   - io.Reader: content reader
 //   - []io.Reader: content readers
+//   - FSPath, []FSPath: paths of files, directories, or glob patterns in fs.FS
+//   - *OrderedMap: a loaded document
+//   - *Overrides: values set at paths, from ParseOverrides or EnvOverrides
 func (m *Merger) MergeAs(format Format, inputs ...interface{}) ([]byte, error) {
-	target := ordered.New()
-	for _, input := range inputs {
-		err := m.mergeToMapAs(format, input, target)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	target, err := m.merge(context.Background(), format, inputs, nil)
+	if err != nil {
+		return nil, err
+	}
+	return m.marshal(target)
+}
+
+// MergeContext merges inputs like Merge does, and stops when ctx is done,
+// returning a *MergeError wrapping the error of ctx.
+//
+// It is useful to merge untrusted inputs with the limits, see
+// WithMaxInputSize, WithMaxDepth and WithMaxKeys.
+func (m *Merger) MergeContext(ctx context.Context, inputs ...interface{}) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	target, err := m.merge(ctx, FormatAuto, inputs, nil)
+	if err != nil {
+		return nil, err
+	}
+	return m.marshal(target)
+}
+
+// mergeState is the state of a single merge call
+type mergeState struct {
+	target  *ordered.Map
+	tracker *merge.Tracker
+	// includes are the files on the include chain, and depth
+	// is the depth of nested includes, see WithIncludes
+	includes []string
+	depth    int
+	// load is the context of loading inputs
+	load *loadContext
+}
+
+// merge merges inputs of the format into a new map,
+// tracking the sources of values with the tracker if not nil.
+func (m *Merger) merge(ctx context.Context, format Format, inputs []interface{}, tracker *merge.Tracker) (*ordered.Map, error) {
+	if m.options.Err != nil {
+		return nil, m.options.Err
+	}
+	if tracker == nil && m.options.needsTracker() {
+		tracker = merge.NewTracker()
+	}
+	s := &mergeState{
+		target:  ordered.New(),
+		tracker: tracker,
+		load: &loadContext{
+			ctx:       ctx,
+			fsys:      m.fileSystem(),
+			maxSize:   m.options.MaxInputSize,
+			maxDepth:  m.options.MaxDepth,
+			maxKeys:   m.options.MaxKeys,
+			useNumber: m.options.UseNumber,
+		},
+	}
+	for i, input := range inputs {
+		name := fmt.Sprintf("inputs[%d]", i)
+		if err := ctx.Err(); err != nil {
+			return nil, &MergeError{Input: name, Err: err}
+		}
+		err := m.mergeToMapAs(s, format, name, input)
 		if err != nil {
 			return nil, err
 		}
 	}
-	err := m.options.apply(target)
-	if err != nil {
+	if err := m.options.visitMerged(PhaseAfterMerge, s.target, s.tracker); err != nil {
 		return nil, err
 	}
-	if m.options.MarshalIndent != "" {
-		return json.MarshalIndent(target, m.options.MarshalPrefix, m.options.MarshalIndent)
+	if err := m.options.apply(s.target, s.tracker); err != nil {
+		err := newMergeError("", err).(*MergeError)
+		if err.Input == "" && s.tracker != nil {
+			err.Input = s.tracker.Provenance(s.target)[err.Path]
+		}
+		return nil, err
 	}
-	return json.Marshal(target)
+	if m.options.Lookup != nil {
+		if err := interpolate.Expand(s.target, m.options.Lookup); err != nil {
+			err := newMergeError("", err).(*MergeError)
+			if s.tracker != nil {
+				err.Input = s.tracker.Provenance(s.target)[err.Path]
+			}
+			return nil, err
+		}
+	}
+	if err := m.options.visitMerged(PhaseAfterApply, s.target, s.tracker); err != nil {
+		return nil, err
+	}
+	if err := m.options.validate(s.target, s.tracker); err != nil {
+		return nil, err
+	}
+	return s.target, nil
 }
 
-func (m *Merger) mergeToMapAs(formatName Format, input interface{}, target *ordered.Map) error {
+// mergeToMapAs loads input of the format and merges into the target,
+// name is the name of input, used if it's not a file.
+func (m *Merger) mergeToMapAs(s *mergeState, formatName Format, name string, input interface{}) error {
 	if formatName == FormatAuto {
-		return m.mergeToMap(input, target)
+		return m.mergeToMap(s, name, input)
 	}
 	f, found := m.loadersByName[formatName]
 	if !found {
-		return fmt.Errorf("unknown format: %s", formatName)
+		return &MergeError{Input: name, Err: fmt.Errorf("unknown format: %s", formatName)}
+	}
+	input, err := expandInput(s.load.fsys, input, func(ext string) bool {
+		return contains(f.Extensions, ext)
+	})
+	if err != nil {
+		return err
 	}
-	maps, err := f.Load(input)
+	docs, err := f.Load(s.load, name, input)
 	if err != nil {
 		return err
 	}
-	return merge.OrderedMaps(target, maps, m.options.TypeOverride)
+	return m.mergeDocuments(s, docs)
 }
 
-func (m *Merger) mergeToMap(input interface{}, target *ordered.Map) error {
+func (m *Merger) mergeToMap(s *mergeState, name string, input interface{}) error {
 	if input == nil {
 		return nil
 	}
+	fsys := s.load.fsys
+	input, err := expandInput(fsys, input, func(ext string) bool {
+		_, found := m.loadersByExt[ext]
+		return found
+	})
+	if err != nil {
+		return err
+	}
 	switch v := input.(type) {
 	case string:
-		// load by file extension
-		if ext := getExtension(v); ext != "" {
-			lext := strings.ToLower(ext)
-			if f, found := m.loadersByExt[lext]; found {
-				mp, err := f.Load(v)
-				if err != nil {
-					return err
-				}
-				return merge.OrderedMaps(target, mp, m.options.TypeOverride)
-			}
-		}
-		err := m.tryLoaders(v, target)
-		if err != nil {
+		return m.mergeFile(s, fsys, v)
+	case FSPath:
+		return m.mergeFile(s, fileSystemOf(v.FS, fsys), v.Path)
+	case *ordered.Map:
+		return m.mergeDocuments(s, []*document{{Map: ordered.DeepCopy(v).(*ordered.Map), Source: name}})
+	case *Overrides:
+		return m.mergeDocuments(s, []*document{{Overrides: v, Source: name}})
+	case []byte:
+		if err := s.load.checkSize(name, v); err != nil {
 			return err
 		}
+		return m.mergeContent(s, name, v)
 	case io.Reader:
-		// read into []byte in case it's drained when try different load
-		bs, err := io.ReadAll(v)
-		if err != nil {
-			return err
-		}
-		err = m.tryLoaders(bs, target)
+		bs, err := s.load.readAll(name, v)
 		if err != nil {
 			return err
 		}
+		return m.mergeContent(s, name, bs)
 	case []string:
 		for _, v := range v {
-			err := m.mergeToMap(v, target)
+			err := m.mergeToMap(s, v, v)
 			if err != nil {
 				return err
 			}
 		}
-	case []io.Reader:
+	case []FSPath:
 		for _, v := range v {
-			err := m.mergeToMap(v, target)
+			err := m.mergeToMap(s, v.Path, v)
+			if err != nil {
+				return err
+			}
+		}
+	case [][]byte:
+		for i, v := range v {
+			err := m.mergeToMap(s, fmt.Sprintf("%s[%d]", name, i), v)
+			if err != nil {
+				return err
+			}
+		}
+	case []io.Reader:
+		for i, v := range v {
+			err := m.mergeToMap(s, fmt.Sprintf("%s[%d]", name, i), v)
 			if err != nil {
 				return err
 			}
 		}
 	default:
-		return m.tryLoaders(v, target)
+		return &MergeError{Input: name, Err: fmt.Errorf("unsupported input type: %T", input)}
 	}
 	return nil
 }
 
-func (m *Merger) tryLoaders(input interface{}, target *ordered.Map) error {
-	var errs []string
-	for _, f := range m.loadersByName {
-		mp, err := f.Load(input)
+// mergeFile loads the file of fsys and merges into the target.
+func (m *Merger) mergeFile(s *mergeState, fsys fileSystem, file string) error {
+	docs, err := m.loadFile(s, fsys, file)
+	if err != nil {
+		return err
+	}
+	return m.mergeDocuments(s, docs)
+}
+
+// loadFile loads the file of fsys by its extension,
+// or tries all loaders if the extension is unknown.
+func (m *Merger) loadFile(s *mergeState, fsys fileSystem, file string) ([]*document, error) {
+	if f, found := m.loadersByExt[getExtension(file)]; found {
+		return f.loadFiles(s.load, fsys, []string{file})
+	}
+	bs, err := s.load.readFile(fsys, file)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := m.tryLoaders(s.load, file, bs)
+	if err != nil {
+		return nil, err
+	}
+	for _, doc := range docs {
+		doc.File, doc.FS = file, fsys
+	}
+	return docs, nil
+}
+
+// fileSystem returns the file system to load files from
+func (m *Merger) fileSystem() fileSystem {
+	return newFileSystem(m.options.FS)
+}
+
+// tryLoaders tries the detected loaders in order to load
+// the content of input named name.
+func (m *Merger) tryLoaders(c *loadContext, name string, content []byte) ([]*document, error) {
+	fe := &FormatError{}
+	loaders, rejected := m.detectLoaders(content)
+	for _, f := range loaders {
+		doc, err := f.load(c, name, content)
 		if err == nil {
-			return merge.OrderedMaps(target, mp, m.options.TypeOverride)
+			return []*document{doc}, nil
+		}
+		if errors.Is(err, ErrTooDeep) || errors.Is(err, ErrTooManyKeys) {
+			// the format is right, but the content exceeds the limits
+			return nil, err
+		}
+		var me *MergeError
+		if !errors.As(err, &me) {
+			me = &MergeError{Input: name, Err: err}
 		}
-		errs = append(errs, fmt.Sprintf("[%s] %s", f.Name, err))
+		fe.Formats = append(fe.Formats, f.Name)
+		fe.Errors = append(fe.Errors, me)
+	}
+	for _, f := range rejected {
+		fe.Rejected = append(fe.Rejected, f.Name)
+	}
+	err := &MergeError{Input: name, Err: fe}
+	if len(fe.Errors) > 0 {
+		err.Line, err.Column = fe.Errors[0].Line, fe.Errors[0].Column
 	}
-	return fmt.Errorf("tried all formats but failed: %s", strings.Join(errs, "; "))
+	return nil, err
+}
+
+// mergeContent loads the content of input named name,
+// and merges into the target.
+func (m *Merger) mergeContent(s *mergeState, name string, content []byte) error {
+	docs, err := m.tryLoaders(s.load, name, content)
+	if err != nil {
+		return err
+	}
+	return m.mergeDocuments(s, docs)
+}
+
+// mergeDocuments merges maps and applies patches to target in order
+func (m *Merger) mergeDocuments(s *mergeState, docs []*document) error {
+	opts := m.options.mergeOptions(s.tracker)
+	opts.Context = s.load.ctx
+	for _, doc := range docs {
+		if err := m.checkLimits(s, doc); err != nil {
+			return err
+		}
+		if doc.Map != nil && m.options.Includes {
+			if err := m.resolveIncludes(s, doc); err != nil {
+				return err
+			}
+		}
+		// included documents are visited with the including one
+		if doc.Map != nil && s.depth == 0 {
+			if err := m.options.visitDocument(doc); err != nil {
+				return err
+			}
+		}
+		var observer patch.Observer
+		if s.tracker != nil {
+			observer = &patchTracker{tracker: s.tracker, source: doc.Source}
+		}
+		var err error
+		switch {
+		case doc.Patch != nil:
+			err = doc.Patch.ApplyObserved(s.target, observer)
+		case doc.Overrides != nil:
+			err = doc.Overrides.apply(s.target, s.load.useNumber, observer)
+		default:
+			opts.Source = doc.Source
+			err = opts.OrderedMaps(s.target, []*ordered.Map{doc.Map})
+		}
+		if err != nil {
+			return newMergeError(doc.Source, err)
+		}
+	}
+	return nil
 }
 
 func getExtension(filename string) string {
@@ -0,0 +1,124 @@
+package jsons_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/qjebbs/go-jsons"
+)
+
+func TestMergerConcurrent(t *testing.T) {
+	m := jsons.NewMerger(jsons.WithMergeBy("tag"))
+	a := []byte(`{"list":[{"tag":"a","value":1}]}`)
+	b := []byte(`{"list":[{"tag":"a","value":2}]}`)
+	want := `{"list":[{"tag":"a","value":2}]}`
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for i := 0; i < 10; i++ {
+		wg.Add(5)
+		go func() {
+			defer wg.Done()
+			got, err := m.Merge(a, b)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if string(got) != want {
+				errs <- fmt.Errorf("want %s, got %s", want, got)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, _, err := m.MergeWithProvenance(a, b); err != nil {
+				errs <- err
+			}
+		}()
+		go func(i int) {
+			defer wg.Done()
+			format := jsons.Format(fmt.Sprintf("format%d", i))
+			err := m.RegisterLoader(format, []string{"." + string(format)}, nopLoader)
+			if err != nil {
+				errs <- err
+			}
+			err = m.RegisterEncoder(format, func(o *jsons.OrderedMap) ([]byte, error) {
+				return nil, nil
+			})
+			if err != nil {
+				errs <- err
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := m.Extensions(); err != nil {
+				errs <- err
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			c := m.With(jsons.WithOrderBy("value"))
+			if _, err := c.MergeTo(jsons.FormatJSON, a, b); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestMergerWith(t *testing.T) {
+	m := jsons.NewMerger(jsons.WithMergeBy("tag"))
+	err := m.RegisterLoader("a", []string{".a"}, nopLoader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := m.With(jsons.WithMergeBy("name"), jsons.WithIndent("", "  "))
+	err = c.RegisterLoader("b", []string{".b"}, nopLoader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the variant has the loaders of original, but not vice versa
+	got, err := c.Extensions("a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Errorf("want 2 extensions, got %v", got)
+	}
+	if _, err := m.Extensions("b"); err == nil {
+		t.Error("want error, got nil")
+	}
+	input := []byte(`{"list":[{"tag":"a","name":"x"},{"tag":"b","name":"x"}]}`)
+	got1, err := m.Merge(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"list":[{"tag":"a","name":"x"},{"tag":"b","name":"x"}]}`; string(got1) != want {
+		t.Errorf("want %s, got %s", want, got1)
+	}
+	got2, err := c.Merge([]byte(`{"a":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\n  \"a\": 1\n}"; string(got2) != want {
+		t.Errorf("want %q, got %q", want, got2)
+	}
+	// options of the clone never affect the original
+	m2 := m.Clone()
+	m3 := m2.With(jsons.WithMergeBy("name"))
+	m4 := m2.With(jsons.WithOrderBy("tag"))
+	got3, err := m3.Merge(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got4, err := m4.Merge(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got3) == string(got4) {
+		t.Errorf("variants should differ, got %s", got3)
+	}
+}
@@ -0,0 +1,73 @@
+package jsons
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// EncodeFunc encodes the merged *OrderedMap to bytes of a specific format
+type EncodeFunc func(*OrderedMap) ([]byte, error)
+
+// RegisterEncoder registers a new format encoder, which is used by
+// MergeTo to encode the merged result. The fields order is kept
+// if the encoder respects the order of OrderedMap.Keys.
+//
+// Registering an existing format replaces its encoder, including
+// the built-in FormatJSON one, which is used by Merge and MergeAs.
+func (m *Merger) RegisterEncoder(format Format, fn EncodeFunc) error {
+	if format == FormatAuto {
+		return fmt.Errorf("cannot register with reserved name: '%s'", FormatAuto)
+	}
+	if fn == nil {
+		return fmt.Errorf("nil encoder for format '%s'", format)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.encoders[format] = fn
+	return nil
+}
+
+// MergeTo merges inputs into a single document, and encodes it
+// with the encoder registered for format.
+//
+// It detects the format of inputs like Merge does.
+func (m *Merger) MergeTo(format Format, inputs ...interface{}) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	encode, found := m.encoder(format)
+	if !found {
+		return nil, fmt.Errorf("unknown output format: %s", format)
+	}
+	target, err := m.merge(context.Background(), FormatAuto, inputs, nil)
+	if err != nil {
+		return nil, err
+	}
+	return encode(target)
+}
+
+// encodeJSON is the built-in FormatJSON encoder
+func (m *Merger) encodeJSON(target *OrderedMap) ([]byte, error) {
+	if m.options.MarshalIndent != "" {
+		return json.MarshalIndent(target, m.options.MarshalPrefix, m.options.MarshalIndent)
+	}
+	return json.Marshal(target)
+}
+
+// encoder returns the encoder of format, where FormatJSON
+// falls back to the built-in one if not registered.
+func (m *Merger) encoder(format Format) (EncodeFunc, bool) {
+	if fn, found := m.encoders[format]; found {
+		return fn, true
+	}
+	if format == FormatJSON {
+		return m.encodeJSON, true
+	}
+	return nil, false
+}
+
+// marshal encodes target with the FormatJSON encoder
+func (m *Merger) marshal(target *OrderedMap) ([]byte, error) {
+	encode, _ := m.encoder(FormatJSON)
+	return encode(target)
+}
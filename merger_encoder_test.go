@@ -0,0 +1,82 @@
+package jsons_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/qjebbs/go-jsons"
+)
+
+func TestMergeTo(t *testing.T) {
+	m := jsons.NewMerger()
+	// encodes top-level fields as "key=value" lines
+	err := m.RegisterEncoder("kv", func(o *jsons.OrderedMap) ([]byte, error) {
+		var sb strings.Builder
+		for _, k := range o.Keys {
+			fmt.Fprintf(&sb, "%s=%v\n", k, o.Values[k])
+		}
+		return []byte(sb.String()), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.MergeTo(
+		"kv",
+		[]byte(`{"z":1,"a":"x"}`),
+		[]byte(`{"b":true,"z":2}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "z=2\na=x\nb=true\n"
+	if string(got) != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	got, err = m.MergeTo(jsons.FormatJSON, []byte(`{"z":1,"a":"x"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != `{"z":1,"a":"x"}` {
+		t.Errorf("unexpected json: %s", got)
+	}
+}
+
+func TestMergeToError(t *testing.T) {
+	m := jsons.NewMerger()
+	_, err := m.MergeTo("unknown", []byte(`{}`))
+	if err == nil {
+		t.Error("want error, got nil")
+	}
+	_, err = m.MergeTo(jsons.FormatJSON, []byte(`{`))
+	if err == nil {
+		t.Error("want error, got nil")
+	}
+	err = m.RegisterEncoder(jsons.FormatAuto, func(o *jsons.OrderedMap) ([]byte, error) {
+		return nil, nil
+	})
+	if err == nil {
+		t.Error("want error, got nil")
+	}
+	err = m.RegisterEncoder("nil", nil)
+	if err == nil {
+		t.Error("want error, got nil")
+	}
+}
+
+func TestRegisterEncoderJSON(t *testing.T) {
+	m := jsons.NewMerger()
+	err := m.RegisterEncoder(jsons.FormatJSON, func(o *jsons.OrderedMap) ([]byte, error) {
+		return []byte(strings.Join(o.Keys, ",")), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.Merge([]byte(`{"b":1,"a":2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "b,a" {
+		t.Errorf("want %q, got %q", "b,a", got)
+	}
+}
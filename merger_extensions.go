@@ -5,6 +5,8 @@ import "fmt"
 // Extensions get supported extensions of given formats.
 // If formatNames is empty or contains FormatAuto, it returns all extensions.
 func (m *Merger) Extensions(formatNames ...Format) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	if len(formatNames) == 0 || contains(formatNames, FormatAuto) {
 		return m.getAllExtensions(), nil
 	}
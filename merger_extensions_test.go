@@ -10,7 +10,7 @@ import (
 
 func TestGetExtensions(t *testing.T) {
 	m := jsons.NewMerger()
-	m.RegisterLoader("a", []string{".a1", ".a2"}, nil)
+	m.RegisterLoader("a", []string{".a1", ".a2"}, nopLoader)
 	want := []string{".a1", ".a2"}
 	got, err := m.Extensions("a")
 	if err != nil {
@@ -23,15 +23,15 @@ func TestGetExtensions(t *testing.T) {
 
 func TestGetAllExtensions(t *testing.T) {
 	m := jsons.NewMerger()
-	err := m.RegisterLoader("a", []string{".a1", ".a2"}, nil)
+	err := m.RegisterLoader("a", []string{".a1", ".a2"}, nopLoader)
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = m.RegisterLoader("b", []string{".b1", ".b2"}, nil)
+	err = m.RegisterLoader("b", []string{".b1", ".b2"}, nopLoader)
 	if err != nil {
 		t.Fatal(err)
 	}
-	want := []string{".a1", ".a2", ".b1", ".b2", ".json"}
+	want := []string{".a1", ".a2", ".b1", ".b2", ".json", ".jsonc", ".jsonpatch"}
 	got, err := m.Extensions(jsons.FormatAuto)
 	if err != nil {
 		t.Fatal(err)
@@ -0,0 +1,220 @@
+package jsons
+
+import (
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// expandInput expands directories and glob patterns of path inputs into
+// files, whose extensions are accepted by accept, where fsys is the file
+// system of inputs without their own. Other inputs are returned as is.
+func expandInput(fsys fileSystem, input interface{}, accept func(ext string) bool) (interface{}, error) {
+	switch v := input.(type) {
+	case string:
+		files, ok, err := expandPath(fsys, v, accept)
+		if err != nil || !ok {
+			return input, err
+		}
+		return files, nil
+	case []string:
+		var expanded []string
+		for _, p := range v {
+			files, ok, err := expandPath(fsys, p, accept)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				files = []string{p}
+			}
+			expanded = append(expanded, files...)
+		}
+		return expanded, nil
+	case FSPath:
+		files, ok, err := expandPath(fileSystemOf(v.FS, fsys), v.Path, accept)
+		if err != nil || !ok {
+			return input, err
+		}
+		expanded := make([]FSPath, len(files))
+		for i, f := range files {
+			expanded[i] = FSPath{FS: v.FS, Path: f}
+		}
+		return expanded, nil
+	case []FSPath:
+		var expanded []FSPath
+		for _, p := range v {
+			files, ok, err := expandPath(fileSystemOf(p.FS, fsys), p.Path, accept)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				expanded = append(expanded, p)
+				continue
+			}
+			for _, f := range files {
+				expanded = append(expanded, FSPath{FS: p.FS, Path: f})
+			}
+		}
+		return expanded, nil
+	}
+	return input, nil
+}
+
+// expandPath expands the directory or glob pattern p into files, whose
+// extensions are accepted by accept, in natural order of their paths.
+// ok is false if p is neither a directory nor a glob pattern.
+//
+// Files of a directory are listed non-recursively, while "**" in
+// a glob pattern matches zero or more directories.
+func expandPath(fsys fileSystem, p string, accept func(ext string) bool) (files []string, ok bool, err error) {
+	info, err := fsys.Stat(p)
+	switch {
+	case err == nil && info.IsDir():
+		files, err = listDir(fsys, p)
+	case err == nil || !isGlob(p):
+		return nil, false, nil
+	default:
+		files, err = glob(fsys, p)
+	}
+	if err != nil {
+		return nil, true, &MergeError{Input: p, Err: err}
+	}
+	filtered := files[:0]
+	for _, f := range files {
+		if accept(getExtension(f)) {
+			filtered = append(filtered, f)
+		}
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return naturalLess(filtered[i], filtered[j])
+	})
+	return filtered, true, nil
+}
+
+// listDir lists the files of dir non-recursively
+func listDir(fsys fileSystem, dir string) ([]string, error) {
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			files = append(files, fsys.Join(dir, e.Name()))
+		}
+	}
+	return files, nil
+}
+
+func isGlob(p string) bool {
+	return strings.ContainsAny(p, "*?[")
+}
+
+// glob returns the files matching pattern, where "**" matches
+// zero or more directories.
+func glob(fsys fileSystem, pattern string) ([]string, error) {
+	segments := strings.Split(fsys.ToSlash(pattern), "/")
+	for _, seg := range segments {
+		if _, err := path.Match(seg, ""); err != nil {
+			return nil, err
+		}
+	}
+	// walk from the longest leading directory without meta characters
+	n := 0
+	for n < len(segments)-1 && !isGlob(segments[n]) {
+		n++
+	}
+	root := strings.Join(segments[:n], "/")
+	if root == "" && n > 0 {
+		// absolute pattern like "/*.json"
+		root = "/"
+	}
+	if root == "" {
+		root = "."
+	}
+	root = fsys.FromSlash(root)
+	patterns := segments[n:]
+	recursive := contains(patterns, "**")
+	var files []string
+	err := fsys.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := fsys.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if !recursive && rel != "." && strings.Count(rel, "/")+1 >= len(patterns) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if matchSegments(patterns, strings.Split(rel, "/")) {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// matchSegments tells if the path segments match the pattern segments
+func matchSegments(patterns, segments []string) bool {
+	if len(patterns) == 0 {
+		return len(segments) == 0
+	}
+	if patterns[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(patterns[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	// pattern is validated before
+	matched, _ := path.Match(patterns[0], segments[0])
+	return matched && matchSegments(patterns[1:], segments[1:])
+}
+
+// naturalLess compares a and b in natural order, where digit
+// sequences are compared by their numeric values, e.g.:
+//
+//	"2_a.json" < "10_b.json"
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		da, db := digitPrefix(a), digitPrefix(b)
+		if da != "" && db != "" {
+			na := strings.TrimLeft(da, "0")
+			nb := strings.TrimLeft(db, "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			a, b = a[len(da):], b[len(db):]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+// digitPrefix returns the leading digits of s
+func digitPrefix(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
+}
@@ -0,0 +1,93 @@
+package jsons
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestNaturalLess(t *testing.T) {
+	got := []string{"10_b.json", "2_a.json", "01_base.json", "a10.json", "a9.json", "a.json", "b.json"}
+	sort.Slice(got, func(i, j int) bool {
+		return naturalLess(got[i], got[j])
+	})
+	want := []string{"01_base.json", "2_a.json", "10_b.json", "a.json", "a9.json", "a10.json", "b.json"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestMergeDirAndGlob(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"10_override.json":    `{"log":{"level":"error"},"list":[10]}`,
+		"2_second.json":       `{"log":{"level":"info"},"list":[2]}`,
+		"01_base.json":        `{"log":{"level":"debug"},"list":[1]}`,
+		"readme.txt":          `not a config`,
+		"sub/1_nested.json":   `{"list":[100]}`,
+		"sub/deep/2_a.jsonc":  `{"list":[200]} // comment`,
+		"sub/deep/ignore.txt": `not a config`,
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	testCases := []struct {
+		input interface{}
+		want  string
+	}{
+		{
+			input: dir,
+			want:  `{"log":{"level":"error"},"list":[1,2,10]}`,
+		},
+		{
+			input: filepath.Join(dir, "*.json"),
+			want:  `{"log":{"level":"error"},"list":[1,2,10]}`,
+		},
+		{
+			input: filepath.Join(dir, "**", "*.json*"),
+			want:  `{"log":{"level":"error"},"list":[1,2,10,100,200]}`,
+		},
+		{
+			input: filepath.Join(dir, "sub", "*", "*"),
+			want:  `{"list":[200]}`,
+		},
+		{
+			input: []string{filepath.Join(dir, "sub"), filepath.Join(dir, "0*.json")},
+			want:  `{"list":[100,1],"log":{"level":"debug"}}`,
+		},
+		{
+			input: filepath.Join(dir, "*.yaml"),
+			want:  `{}`,
+		},
+	}
+	m := NewMerger()
+	for _, tc := range testCases {
+		got, err := m.Merge(tc.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tc.want {
+			t.Errorf("%v: want %s, got %s", tc.input, tc.want, got)
+		}
+	}
+	// only files of the format are loaded
+	got, err := m.MergeAs(FormatJSONC, filepath.Join(dir, "**"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"list":[200]}`; string(got) != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+	_, err = m.Merge(filepath.Join(dir, "[.json"))
+	if err == nil {
+		t.Error("want error, got nil")
+	}
+}
@@ -0,0 +1,156 @@
+package jsons
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/qjebbs/go-jsons/internal/ordered"
+	"github.com/qjebbs/go-jsons/internal/pointer"
+)
+
+var (
+	// ErrIncludeCycle is the error of including a file being included
+	ErrIncludeCycle = errors.New("include cycle")
+	// ErrIncludeDepth is the error of exceeding the max depth of nested includes
+	ErrIncludeDepth = errors.New("max include depth exceeded")
+)
+
+// includeDirective returns the name of the include directive
+func (r *options) includeDirective() string {
+	prefix := r.Directives
+	if prefix == "" {
+		prefix = DefaultDirectivePrefix
+	}
+	return prefix + "include"
+}
+
+// resolveIncludes replaces the objects holding include
+// directives in doc with the included results.
+func (m *Merger) resolveIncludes(s *mergeState, doc *document) error {
+	v, err := m.resolveIncludesIn(s, doc, nil, doc.Map)
+	if err != nil {
+		return err
+	}
+	doc.Map = v.(*ordered.Map)
+	return nil
+}
+
+// resolveIncludesIn resolves the include directives in v at path,
+// and returns the resolved value.
+func (m *Merger) resolveIncludesIn(s *mergeState, doc *document, path []string, v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case *ordered.Map:
+		for _, k := range v.Keys {
+			resolved, err := m.resolveIncludesIn(s, doc, pointer.Append(path, k), v.Values[k])
+			if err != nil {
+				return nil, err
+			}
+			v.Values[k] = resolved
+		}
+		if _, ok := v.Values[m.options.includeDirective()]; ok {
+			return m.include(s, doc, path, v)
+		}
+	case []interface{}:
+		for i, e := range v {
+			resolved, err := m.resolveIncludesIn(s, doc, pointer.Append(path, strconv.Itoa(i)), e)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = resolved
+		}
+	}
+	return v, nil
+}
+
+// include merges the files included by the object v at path, and
+// the own fields of v into a new map.
+func (m *Merger) include(s *mergeState, doc *document, path []string, v *ordered.Map) (*ordered.Map, error) {
+	directive := m.options.includeDirective()
+	newError := func(err error) error {
+		return &MergeError{
+			Input: doc.Source,
+			Path:  pointer.Format(pointer.Append(path, directive)),
+			Err:   err,
+		}
+	}
+	files, err := includedFiles(v.Values[directive])
+	if err != nil {
+		return nil, newError(err)
+	}
+	v.Remove(directive)
+	fsys := doc.FS
+	if fsys == nil {
+		fsys = m.fileSystem()
+	}
+	// the files on the include chain, to detect cycles
+	chain := s.includes[:len(s.includes):len(s.includes)]
+	if doc.File != "" {
+		// clean the top-level path the same way as included ones
+		chain = append(chain, fsys.Resolve("", doc.File))
+	}
+	included := &mergeState{
+		target:   ordered.New(),
+		tracker:  s.tracker,
+		includes: chain,
+		depth:    s.depth + 1,
+		load:     s.load,
+	}
+	for _, file := range files {
+		file = fsys.Resolve(doc.File, file)
+		expanded, ok, err := expandPath(fsys, file, func(ext string) bool {
+			_, found := m.loadersByExt[ext]
+			return found
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			expanded = []string{file}
+		}
+		for _, file := range expanded {
+			if contains(chain, file) {
+				return nil, newError(fmt.Errorf("%w: %s", ErrIncludeCycle, file))
+			}
+			if included.depth > m.options.MaxIncludeDepth {
+				return nil, newError(fmt.Errorf("%w: %d", ErrIncludeDepth, m.options.MaxIncludeDepth))
+			}
+			docs, err := m.loadFile(s, fsys, file)
+			if err != nil {
+				return nil, err
+			}
+			if err := m.mergeDocuments(included, docs); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// own fields override the included ones
+	opts := m.options.mergeOptions(s.tracker)
+	opts.Context = s.load.ctx
+	opts.Source = doc.Source
+	if err := opts.OrderedMapAt(path, included.target, v); err != nil {
+		return nil, newMergeError(doc.Source, err)
+	}
+	return included.target, nil
+}
+
+// includedFiles returns the files of the include directive value v,
+// which is a string or an array of strings.
+func includedFiles(v interface{}) ([]string, error) {
+	switch v := v.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		files := make([]string, 0, len(v))
+		for _, e := range v {
+			file, ok := e.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid include: %v", e)
+			}
+			files = append(files, file)
+		}
+		return files, nil
+	default:
+		return nil, fmt.Errorf("invalid include: %v", v)
+	}
+}
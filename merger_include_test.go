@@ -0,0 +1,152 @@
+package jsons_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/qjebbs/go-jsons"
+)
+
+func TestMergeIncludes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"hosts/a.json": {Data: []byte(`{
+			"$include": "../common/base.json",
+			"dns": {"$include": ["../common/dns.json"], "servers": ["1.1.1.1"]},
+			"log": {"level": "info"}
+		}`)},
+		"common/base.json":         {Data: []byte(`{"log":{"level":"debug","output":"stdout"},"routing":{"$include":"rules.d"}}`)},
+		"common/dns.json":          {Data: []byte(`{"servers":["8.8.8.8"],"strategy":"ipv4"}`)},
+		"common/rules.d/1_a.json":  {Data: []byte(`{"rules":["a"]}`)},
+		"common/rules.d/10_b.json": {Data: []byte(`{"rules":["b"]}`)},
+	}
+	m := jsons.NewMerger(jsons.WithFS(fsys), jsons.WithIncludes(0))
+	got, prov, err := m.MergeWithProvenance("hosts/a.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"log":{"level":"info","output":"stdout"},"routing":{"rules":["a","b"]},"dns":{"servers":["8.8.8.8","1.1.1.1"],"strategy":"ipv4"}}`
+	if string(got) != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+	wantProv := jsons.Provenance{
+		"/log/level":       "hosts/a.json",
+		"/log/output":      "common/base.json",
+		"/routing/rules/0": "common/rules.d/1_a.json",
+		"/routing/rules/1": "common/rules.d/10_b.json",
+		"/dns/servers/0":   "common/dns.json",
+		"/dns/servers/1":   "hosts/a.json",
+		"/dns/strategy":    "common/dns.json",
+	}
+	for k, v := range wantProv {
+		if prov[k] != v {
+			t.Errorf("%s: want %s, got %s", k, v, prov[k])
+		}
+	}
+}
+
+func TestMergeIncludesRelative(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "log.json"), []byte(`{"log":{"level":"debug"}}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := filepath.Join(dir, "a.json")
+	err = os.WriteFile(a, []byte(`{"%include":"log.json"}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := jsons.NewMerger(jsons.WithIncludes(0), jsons.WithDirectives("%"))
+	got, err := m.Merge(a, []byte(`{"%include":"`+filepath.ToSlash(filepath.Join(dir, "log.json"))+`","port":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"log":{"level":"debug"},"port":1}`; string(got) != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+	// disabled by default
+	got, err = jsons.Merge([]byte(`{"$include":"log.json"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"$include":"log.json"}`; string(got) != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
+
+func TestMergeIncludesError(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.json":       {Data: []byte(`{"x":{"$include":"b.json"}}`)},
+		"b.json":       {Data: []byte(`{"$include":"a.json"}`)},
+		"self.json":    {Data: []byte(`{"$include":"./self.json"}`)},
+		"d1.json":      {Data: []byte(`{"$include":"d2.json"}`)},
+		"d2.json":      {Data: []byte(`{"$include":"d3.json"}`)},
+		"d3.json":      {Data: []byte(`{"a":1}`)},
+		"invalid.json": {Data: []byte(`{"$include":[1]}`)},
+	}
+	m := jsons.NewMerger(jsons.WithFS(fsys), jsons.WithIncludes(1))
+	testCases := []struct {
+		input string
+		want  *jsons.MergeError
+	}{
+		{
+			input: "a.json",
+			want:  &jsons.MergeError{Input: "b.json", Path: "/$include", Err: jsons.ErrIncludeCycle},
+		},
+		{
+			input: "self.json",
+			want:  &jsons.MergeError{Input: "self.json", Path: "/$include", Err: jsons.ErrIncludeCycle},
+		},
+		{
+			input: "d1.json",
+			want:  &jsons.MergeError{Input: "d2.json", Path: "/$include", Err: jsons.ErrIncludeDepth},
+		},
+		{
+			input: "invalid.json",
+			want:  &jsons.MergeError{Input: "invalid.json", Path: "/$include"},
+		},
+		{
+			input: "missing.json",
+			want:  &jsons.MergeError{Input: "missing.json"},
+		},
+	}
+	for _, tc := range testCases {
+		_, err := m.Merge(tc.input)
+		var me *jsons.MergeError
+		if !errors.As(err, &me) {
+			t.Errorf("%s: want *jsons.MergeError, got %T: %v", tc.input, err, err)
+			continue
+		}
+		if me.Input != tc.want.Input || me.Path != tc.want.Path {
+			t.Errorf("%s: want %s %s, got %s %s", tc.input, tc.want.Input, tc.want.Path, me.Input, me.Path)
+		}
+		if tc.want.Err != nil && !errors.Is(err, tc.want.Err) {
+			t.Errorf("%s: want %v, got %v", tc.input, tc.want.Err, err)
+		}
+	}
+	_, err := m.Merge([]byte(`{"$include":"missing.json"}`))
+	if err == nil {
+		t.Error("want error, got nil")
+	}
+}
+
+func TestMergeIncludesCycleUnclean(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "a.json"), []byte(`{"$include":"a.json"}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := jsons.NewMerger(jsons.WithIncludes(0))
+	// the cycle is detected in the top-level file, not one level deeper
+	a := dir + string(filepath.Separator) + "." + string(filepath.Separator) + "a.json"
+	_, err = m.Merge(a)
+	var me *jsons.MergeError
+	if !errors.As(err, &me) || !errors.Is(err, jsons.ErrIncludeCycle) {
+		t.Fatalf("want %v, got %v", jsons.ErrIncludeCycle, err)
+	}
+	if me.Input != a {
+		t.Errorf("want input %s, got %s", a, me.Input)
+	}
+}
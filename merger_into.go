@@ -0,0 +1,174 @@
+package jsons
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/qjebbs/go-jsons/internal/merge"
+	"github.com/qjebbs/go-jsons/internal/ordered"
+	"github.com/qjebbs/go-jsons/internal/pointer"
+)
+
+// MergeInto merges inputs like Merge does, and decodes the result
+// into dst like json.Unmarshal does, where dst must be a non-nil pointer.
+//
+// With WithDisallowUnknownFields, fields not found in the destination
+// are rejected, e.g.:
+//
+//	a.json: /log/levle: unknown field
+func (m *Merger) MergeInto(dst interface{}, inputs ...interface{}) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var tracker *merge.Tracker
+	if m.options.DisallowUnknownFields {
+		tracker = merge.NewTracker()
+	}
+	target, err := m.merge(context.Background(), FormatAuto, inputs, tracker)
+	if err != nil {
+		return err
+	}
+	if tracker != nil {
+		if err := checkUnknownFields(target, reflect.TypeOf(dst), tracker); err != nil {
+			return err
+		}
+	}
+	bs, err := json.Marshal(target)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(bs))
+	if m.options.UseNumber {
+		dec.UseNumber()
+	}
+	if m.options.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	return dec.Decode(dst)
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// checkUnknownFields checks the fields of v in order, and returns
+// a *MergeError for the first field which is not found in type t.
+func checkUnknownFields(v interface{}, t reflect.Type, tracker *merge.Tracker) error {
+	parent, key, path := unknownField(v, t, nil)
+	if parent == nil {
+		return nil
+	}
+	source, _ := tracker.Source(parent, key)
+	return &MergeError{
+		Input: source,
+		Path:  pointer.Format(path),
+		Err:   ErrUnknownField,
+	}
+}
+
+// unknownField finds the first field of v which is not found in type t,
+// and returns the map holding it, the key and the path of it.
+func unknownField(v interface{}, t reflect.Type, path []string) (*ordered.Map, string, []string) {
+	if t == nil {
+		return nil, "", nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(jsonUnmarshalerType) ||
+		reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		// decoded by the type itself
+		return nil, "", nil
+	}
+	switch v := v.(type) {
+	case *ordered.Map:
+		switch t.Kind() {
+		case reflect.Struct:
+			fields := structFields(t)
+			for _, k := range v.Keys {
+				ft, ok := fieldType(fields, k)
+				if !ok {
+					return v, k, pointer.Append(path, k)
+				}
+				if p, k, path := unknownField(v.Values[k], ft, pointer.Append(path, k)); p != nil {
+					return p, k, path
+				}
+			}
+		case reflect.Map:
+			for _, k := range v.Keys {
+				if p, k, path := unknownField(v.Values[k], t.Elem(), pointer.Append(path, k)); p != nil {
+					return p, k, path
+				}
+			}
+		}
+	case []interface{}:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return nil, "", nil
+		}
+		for i, e := range v {
+			if p, k, path := unknownField(e, t.Elem(), pointer.Append(path, strconv.Itoa(i))); p != nil {
+				return p, k, path
+			}
+		}
+	}
+	return nil, "", nil
+}
+
+// structField is a field of struct decoded by encoding/json
+type structField struct {
+	Name string
+	Type reflect.Type
+}
+
+// structFields returns the fields of struct t decoded by encoding/json,
+// including the ones of embedded structs.
+func structFields(t reflect.Type) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, structFields(ft)...)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, structField{Name: name, Type: f.Type})
+	}
+	return fields
+}
+
+// fieldType returns the type of field named name, which is matched
+// case-insensitively if not found exactly, like encoding/json does.
+func fieldType(fields []structField, name string) (reflect.Type, bool) {
+	for _, f := range fields {
+		if f.Name == name {
+			return f.Type, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.Name, name) {
+			return f.Type, true
+		}
+	}
+	return nil, false
+}
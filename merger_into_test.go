@@ -0,0 +1,133 @@
+package jsons_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/qjebbs/go-jsons"
+)
+
+type logConfig struct {
+	Level  string `json:"level"`
+	Output string `json:"output,omitempty"`
+}
+
+type baseConfig struct {
+	Tag string `json:"tag"`
+}
+
+type outboundConfig struct {
+	baseConfig
+	Protocol string                 `json:"protocol"`
+	Settings map[string]interface{} `json:"settings"`
+}
+
+type config struct {
+	Log       *logConfig         `json:"log"`
+	Outbounds []outboundConfig   `json:"outbounds"`
+	Timeouts  map[string]float64 `json:"timeouts"`
+	Started   time.Time          `json:"started"`
+	Ignored   string             `json:"-"`
+}
+
+func TestMergeInto(t *testing.T) {
+	a := []byte(`{"log":{"level":"info"},"outbounds":[{"tag":"a","protocol":"freedom"}]}`)
+	b := []byte(`{"log":{"Level":"debug"},"outbounds":[{"tag":"b","settings":{"any":1}}],"timeouts":{"dial":1.5}}`)
+	for _, strict := range []bool{false, true} {
+		var opts []jsons.Option
+		if strict {
+			opts = append(opts, jsons.WithDisallowUnknownFields())
+		}
+		var got config
+		err := jsons.NewMerger(opts...).MergeInto(&got, a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := config{
+			Log: &logConfig{Level: "debug"},
+			Outbounds: []outboundConfig{
+				{baseConfig: baseConfig{Tag: "a"}, Protocol: "freedom"},
+				{baseConfig: baseConfig{Tag: "b"}, Settings: map[string]interface{}{"any": float64(1)}},
+			},
+			Timeouts: map[string]float64{"dial": 1.5},
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("want:\n%#v\ngot:\n%#v", want, got)
+		}
+	}
+}
+
+func TestMergeIntoUseNumber(t *testing.T) {
+	a := []byte(`{"outbounds":[{"tag":"a","settings":{"id":9007199254740993}}]}`)
+	var got config
+	err := jsons.NewMerger(jsons.WithUseNumber()).MergeInto(&got, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := got.Outbounds[0].Settings["id"]; id != json.Number("9007199254740993") {
+		t.Errorf("want json.Number 9007199254740993, got %T %v", id, id)
+	}
+}
+
+func TestMergeIntoUnknownField(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.json")
+	err := os.WriteFile(a, []byte(`{"log":{"level":"info"},"outbounds":[{"tag":"a"}]}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := filepath.Join(dir, "b.json")
+	err = os.WriteFile(b, []byte(`{"outbounds":[{"tag":"b","protocl":"freedom"}]}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// unknown fields are ignored by default
+	var got config
+	err = jsons.NewMerger().MergeInto(&got, a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := jsons.NewMerger(jsons.WithDisallowUnknownFields())
+	err = m.MergeInto(&got, a, b)
+	var me *jsons.MergeError
+	if !errors.As(err, &me) {
+		t.Fatalf("want *jsons.MergeError, got %T: %v", err, err)
+	}
+	if !errors.Is(err, jsons.ErrUnknownField) {
+		t.Errorf("want errors.Is ErrUnknownField")
+	}
+	if me.Input != b || me.Path != "/outbounds/1/protocl" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// unknown object field is reported with the input who wrote it
+	err = m.MergeInto(&got, a, []byte(`{"logs":{"level":"debug"}}`))
+	if !errors.As(err, &me) {
+		t.Fatalf("want *jsons.MergeError, got %T: %v", err, err)
+	}
+	want := "inputs[1]: /logs: unknown field"
+	if err.Error() != want {
+		t.Errorf("want %q, got %q", want, err)
+	}
+}
+
+func TestMergeIntoError(t *testing.T) {
+	var got config
+	m := jsons.NewMerger(jsons.WithDisallowUnknownFields())
+	err := m.MergeInto(&got, []byte(`{"log":{"level":1}}`))
+	if err == nil {
+		t.Error("want error, got nil")
+	}
+	err = m.MergeInto(&got, []byte(`{`))
+	if err == nil {
+		t.Error("want error, got nil")
+	}
+	err = m.MergeInto(got, []byte(`{}`))
+	if err == nil {
+		t.Error("want error, got nil")
+	}
+}
@@ -1,26 +1,74 @@
 package jsons
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
+	"github.com/qjebbs/go-jsons/internal/jsonc"
 	"github.com/qjebbs/go-jsons/internal/ordered"
+	"github.com/qjebbs/go-jsons/internal/patch"
 )
 
+// LoaderOption is the option for registering loaders
+type LoaderOption func(l *loader)
+
+// WithLoaderPriority sets the priority of the loader when detecting formats,
+// loaders with higher priority are tried first. The default priority is 0.
+func WithLoaderPriority(priority int) LoaderOption {
+	return func(l *loader) {
+		l.Priority = priority
+	}
+}
+
+// WithLoaderDetect sets the content-sniffing function of the loader.
+//
+// When detecting the format of an input, loaders whose detect functions
+// accept the content are tried first, followed by loaders without detect
+// functions, while loaders whose detect functions reject the content are
+// skipped. Loaders in each group are tried by priority, then by name.
+func WithLoaderDetect(detect func(content []byte) bool) LoaderOption {
+	return func(l *loader) {
+		l.Detect = detect
+	}
+}
+
+// withLoaderDecode sets the decode function of the loader, which
+// respects the limits and WithUseNumber while decoding.
+func withLoaderDecode(fn decodeFunc) LoaderOption {
+	return func(l *loader) {
+		l.DecodeFunc = fn
+	}
+}
+
 // RegisterOrderedLoader register a new format loader that loads data into an ordered map,
 // who keeps the fields order between merges.
-func (m *Merger) RegisterOrderedLoader(name Format, extensions []string, fn LoadOrderedFunc) error {
-	if name == FormatAuto {
+func (m *Merger) RegisterOrderedLoader(name Format, extensions []string, fn LoadOrderedFunc, opts ...LoaderOption) error {
+	if fn == nil {
+		return fmt.Errorf("nil loader for format '%s'", name)
+	}
+	return m.registerLoader(newLoader(name, extensions, fn), opts...)
+}
+
+func (m *Merger) registerLoader(loader *loader, opts ...LoaderOption) error {
+	for _, opt := range opts {
+		opt(loader)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if loader.Name == FormatAuto {
 		return fmt.Errorf("cannot register with reserved name: '%s'", FormatAuto)
 	}
-	if old, found := m.loadersByName[name]; found {
+	if old, found := m.loadersByName[loader.Name]; found {
 		for _, format := range old.Extensions {
 			delete(m.loadersByExt, format)
 		}
 	}
-	loader := newLoader(name, extensions, fn)
-	m.loadersByName[name] = loader
-	for _, ext := range extensions {
+	m.loadersByName[loader.Name] = loader
+	for _, ext := range loader.Extensions {
 		lext := strings.ToLower(ext)
 		if f, found := m.loadersByExt[lext]; found {
 			return fmt.Errorf("file extension '%s' is already registered to '%s'", ext, f.Name)
@@ -32,7 +80,10 @@ func (m *Merger) RegisterOrderedLoader(name Format, extensions []string, fn Load
 
 // RegisterLoader register a new format loader.
 // The fields order is not guaranteed between merges due to the use of map[string]interface{}.
-func (m *Merger) RegisterLoader(name Format, extensions []string, fn LoadFunc) error {
+func (m *Merger) RegisterLoader(name Format, extensions []string, fn LoadFunc, opts ...LoaderOption) error {
+	if fn == nil {
+		return fmt.Errorf("nil loader for format '%s'", name)
+	}
 	fn2 := func(b []byte) (*ordered.Map, error) {
 		m, err := fn(b)
 		if err != nil {
@@ -40,5 +91,106 @@ func (m *Merger) RegisterLoader(name Format, extensions []string, fn LoadFunc) e
 		}
 		return ordered.FromMap(m), nil
 	}
-	return m.RegisterOrderedLoader(name, extensions, fn2)
+	return m.RegisterOrderedLoader(name, extensions, fn2, opts...)
+}
+
+// detectLoaders returns the loaders to try for the content in order,
+// and the loaders whose detect functions reject the content, sorted by name.
+// See WithLoaderDetect for details.
+func (m *Merger) detectLoaders(content []byte) (loaders []*loader, rejected []*loader) {
+	type candidate struct {
+		loader   *loader
+		detected bool
+	}
+	candidates := make([]candidate, 0, len(m.loadersByName))
+	for _, l := range m.loadersByName {
+		if l.Detect == nil {
+			candidates = append(candidates, candidate{loader: l})
+			continue
+		}
+		if l.Detect(content) {
+			candidates = append(candidates, candidate{loader: l, detected: true})
+			continue
+		}
+		rejected = append(rejected, l)
+	}
+	sort.Slice(rejected, func(i, j int) bool {
+		return rejected[i].Name < rejected[j].Name
+	})
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.detected != b.detected {
+			return a.detected
+		}
+		if a.loader.Priority != b.loader.Priority {
+			return a.loader.Priority > b.loader.Priority
+		}
+		return a.loader.Name < b.loader.Name
+	})
+	loaders = make([]*loader, len(candidates))
+	for i, c := range candidates {
+		loaders[i] = c.loader
+	}
+	return loaders, rejected
+}
+
+// startsWith tells if the content starts with c, ignoring
+// leading white spaces and the UTF-8 byte order mark.
+func startsWith(content []byte, c ...byte) bool {
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+	content = bytes.TrimLeft(content, " \t\r\n")
+	return len(content) > 0 && bytes.IndexByte(c, content[0]) >= 0
+}
+
+// loadJSON loads JSON b
+func loadJSON(b []byte) (*ordered.Map, error) {
+	return decodeJSON(b, ordered.DecodeOptions{})
+}
+
+// loadJSONC loads JSON b with comments and trailing commas
+func loadJSONC(b []byte) (*ordered.Map, error) {
+	return decodeJSONC(b, ordered.DecodeOptions{})
+}
+
+// decodeJSON decodes JSON b with the options
+func decodeJSON(b []byte, opts ordered.DecodeOptions) (*ordered.Map, error) {
+	m := &decodingMap{Map: ordered.New(), opts: opts}
+	if err := json.Unmarshal(b, m); err != nil {
+		var le *ordered.LimitError
+		if errors.As(err, &le) {
+			// json.Unmarshal skips the leading spaces before calling
+			// UnmarshalJSON, make the offset relative to b
+			le.Offset += int64(len(b) - len(bytes.TrimLeft(b, " \t\r\n")))
+		}
+		return nil, err
+	}
+	return m.Map, nil
+}
+
+// decodeJSONC decodes JSON b with comments and trailing commas
+func decodeJSONC(b []byte, opts ordered.DecodeOptions) (*ordered.Map, error) {
+	b, err := jsonc.Standardize(b)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSON(b, opts)
+}
+
+// loadPatch loads JSON Patch b, which keeps numbers as json.Number if useNumber
+func loadPatch(b []byte, useNumber bool) (patch.Patch, error) {
+	if useNumber {
+		return patch.ParseUseNumber(b)
+	}
+	return patch.Parse(b)
+}
+
+// decodingMap is an ordered.Map decoded with the options, while
+// json.Unmarshal still validates the syntax before decoding
+type decodingMap struct {
+	*ordered.Map
+	opts ordered.DecodeOptions
+}
+
+func (m *decodingMap) UnmarshalJSON(b []byte) error {
+	return m.Map.UnmarshalJSONWith(b, m.opts)
 }
@@ -0,0 +1,51 @@
+package jsons
+
+import (
+	"context"
+
+	"github.com/qjebbs/go-jsons/internal/merge"
+)
+
+// Provenance maps JSON pointers of the merged values to their sources.
+//
+// A source is the file path of the input, or "inputs[i]" for the i-th
+// non-file input of the merge call, with an extra "[j]" for the j-th
+// element of [][]byte and []io.Reader inputs.
+type Provenance map[string]string
+
+// MergeWithProvenance merges inputs like Merge does, and reports
+// the source which last wrote each leaf and each array element, e.g.:
+//
+//	"/log/level": "b.json"
+//	"/inbounds/0": "a.json"
+//	"/inbounds/0/tag": "a.json"
+func (m *Merger) MergeWithProvenance(inputs ...interface{}) ([]byte, Provenance, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tracker := merge.NewTracker()
+	target, err := m.merge(context.Background(), FormatAuto, inputs, tracker)
+	if err != nil {
+		return nil, nil, err
+	}
+	bs, err := m.marshal(target)
+	if err != nil {
+		return nil, nil, err
+	}
+	return bs, tracker.Provenance(target), nil
+}
+
+// patchTracker tracks the changes made by a patch from source
+type patchTracker struct {
+	tracker *merge.Tracker
+	source  string
+}
+
+// Removed implements patch.Observer
+func (p *patchTracker) Removed(root interface{}, path []string) {
+	p.tracker.Removed(root, path)
+}
+
+// Added implements patch.Observer
+func (p *patchTracker) Added(root interface{}, path []string, moved bool) {
+	p.tracker.Added(root, path, p.source, moved)
+}
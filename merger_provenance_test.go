@@ -0,0 +1,146 @@
+package jsons_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/qjebbs/go-jsons"
+)
+
+func TestMergeWithProvenance(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.json")
+	err := os.WriteFile(a, []byte(`{"log":{"level":"debug","output":"stdout"},"dns":["1.1.1.1"]}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := []byte(`{"log":{"level":"error"},"dns":["8.8.8.8"]}`)
+	c := []string{`{"dns":["9.9.9.9"]}`}
+	m := jsons.NewMerger()
+	got, prov, err := m.MergeWithProvenance(a, b, []byte(c[0]), []byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONEqual(t, []byte(`{"log":{"level":"error","output":"stdout"},"dns":["1.1.1.1","8.8.8.8","9.9.9.9"]}`), got)
+	want := jsons.Provenance{
+		"/log/level":  "inputs[1]",
+		"/log/output": a,
+		"/dns/0":      a,
+		"/dns/1":      "inputs[1]",
+		"/dns/2":      "inputs[2]",
+	}
+	if !reflect.DeepEqual(want, prov) {
+		t.Errorf("want:\n%v\n\ngot:\n%v", want, prov)
+	}
+}
+
+func TestMergeWithProvenanceSlices(t *testing.T) {
+	a := [][]byte{
+		[]byte(`{"a":1}`),
+		[]byte(`{"b":1}`),
+	}
+	b := strings.NewReader(`{"c":1}`)
+	m := jsons.NewMerger()
+	_, prov, err := m.MergeWithProvenance(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := jsons.Provenance{
+		"/a": "inputs[0][0]",
+		"/b": "inputs[0][1]",
+		"/c": "inputs[1]",
+	}
+	if !reflect.DeepEqual(want, prov) {
+		t.Errorf("want:\n%v\n\ngot:\n%v", want, prov)
+	}
+}
+
+func TestMergeWithProvenanceRules(t *testing.T) {
+	a := []byte(`{"outbounds":[{"tag":"a","_order":2},{"tag":"b","_order":1,"port":1}]}`)
+	b := []byte(`{"outbounds":[{"tag":"a","_order":3,"port":2}],"array":[[1],[2]]}`)
+	m := jsons.NewMerger(
+		jsons.WithMergeBy("tag"),
+		jsons.WithOrderByAndRemove("_order"),
+	)
+	got, prov, err := m.MergeWithProvenance(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONEqual(t, []byte(`{"outbounds":[{"tag":"b","port":1},{"tag":"a","port":2}],"array":[[1],[2]]}`), got)
+	want := jsons.Provenance{
+		"/outbounds/0":      "inputs[0]",
+		"/outbounds/0/tag":  "inputs[0]",
+		"/outbounds/0/port": "inputs[0]",
+		"/outbounds/1":      "inputs[0]",
+		"/outbounds/1/tag":  "inputs[1]",
+		"/outbounds/1/port": "inputs[1]",
+		"/array/0":          "inputs[1]",
+		"/array/0/0":        "inputs[1]",
+		"/array/1":          "inputs[1]",
+		"/array/1/0":        "inputs[1]",
+	}
+	if !reflect.DeepEqual(want, prov) {
+		t.Errorf("want:\n%v\n\ngot:\n%v", want, prov)
+	}
+}
+
+func TestMergeWithProvenancePatch(t *testing.T) {
+	a := []byte(`{"a":{"b":1},"c":[1,2,3]}`)
+	b := []byte(`[
+		{"op":"add","path":"/c/1","value":4},
+		{"op":"remove","path":"/c/0"},
+		{"op":"move","from":"/a","path":"/d"},
+		{"op":"replace","path":"/c/2","value":5}
+	]`)
+	m := jsons.NewMerger()
+	got, prov, err := m.MergeWithProvenance(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONEqual(t, []byte(`{"c":[4,2,5],"d":{"b":1}}`), got)
+	want := jsons.Provenance{
+		"/c/0": "inputs[1]",
+		"/c/1": "inputs[0]",
+		"/c/2": "inputs[1]",
+		"/d/b": "inputs[0]",
+	}
+	if !reflect.DeepEqual(want, prov) {
+		t.Errorf("want:\n%v\n\ngot:\n%v", want, prov)
+	}
+}
+
+func TestMergeWithProvenancePatchMove(t *testing.T) {
+	a := []byte(`{"a":1,"c":[1,2,3]}`)
+	b := []byte(`{"c":[4]}`)
+	c := []byte(`[
+		{"op":"move","from":"/a","path":"/b"},
+		{"op":"move","from":"/c/0","path":"/c/3"}
+	]`)
+	m := jsons.NewMerger()
+	got, prov, err := m.MergeWithProvenance(a, b, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONEqual(t, []byte(`{"c":[2,3,4,1],"b":1}`), got)
+	want := jsons.Provenance{
+		"/b":   "inputs[0]",
+		"/c/0": "inputs[0]",
+		"/c/1": "inputs[0]",
+		"/c/2": "inputs[1]",
+		"/c/3": "inputs[0]",
+	}
+	if !reflect.DeepEqual(want, prov) {
+		t.Errorf("want:\n%v\n\ngot:\n%v", want, prov)
+	}
+}
+
+func TestMergeWithProvenanceError(t *testing.T) {
+	m := jsons.NewMerger()
+	_, _, err := m.MergeWithProvenance([]byte(`{`))
+	if err == nil {
+		t.Error("want error, got nil")
+	}
+}
@@ -4,10 +4,25 @@
 
 package jsons
 
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/qjebbs/go-jsons/internal/interpolate"
+	"github.com/qjebbs/go-jsons/internal/merge"
+	"github.com/qjebbs/go-jsons/internal/pointer"
+	"github.com/qjebbs/go-jsons/internal/schema"
+)
+
 // Option is the option for merger
 type Option func(m *Merger)
 
 // PreprocessorFunc is the type of preprocessor function, which can be used to preprocess values before merging.
+//
+// It receives the local key of the value, or "key[i]" for array elements,
+// see WithVisitor for visitors receiving the full path.
 type PreprocessorFunc func(key string, value interface{}) interface{}
 
 // options is the merge options
@@ -15,15 +30,73 @@ type options struct {
 	OrderBy       []field
 	MergeBy       []field
 	TypeOverride  bool
+	MergePatch    bool
+	ArrayRules    []merge.ArrayRule
+	Directives    string
 	MarshalPrefix string
 	MarshalIndent string
 	Preprocessors []PreprocessorFunc
+	Visitors      []visitor
+	// Includes enables the include directive, see WithIncludes
+	Includes        bool
+	MaxIncludeDepth int
+	// Lookup looks up variables for interpolation, nil to disable it
+	Lookup LookupFunc
+	// limits of inputs, 0 for unlimited
+	MaxInputSize int64
+	MaxDepth     int
+	MaxKeys      int
+	// FS is the file system to load files from, nil for the local one
+	FS fs.FS
+	// DisallowUnknownFields makes MergeInto reject unknown fields
+	DisallowUnknownFields bool
+	// UseNumber decodes numbers as json.Number
+	UseNumber bool
+	// Schema validates the merged result if not nil
+	Schema *schema.Schema
+	// Conflicts checks the overwritten values if not nil
+	Conflicts *conflictRule
+
+	// Err is the first error of invalid options, reported on merging
+	Err error
+}
+
+// setErr records the first error of invalid options
+func (r *options) setErr(err error) {
+	if r.Err == nil {
+		r.Err = err
+	}
+}
+
+// clone returns a copy of the options, which shares
+// no underlying arrays with the original.
+func (r *options) clone() options {
+	c := *r
+	c.OrderBy = append([]field(nil), r.OrderBy...)
+	c.MergeBy = append([]field(nil), r.MergeBy...)
+	c.ArrayRules = append([]merge.ArrayRule(nil), r.ArrayRules...)
+	c.Preprocessors = append([]PreprocessorFunc(nil), r.Preprocessors...)
+	c.Visitors = append([]visitor(nil), r.Visitors...)
+	return c
 }
 
 // field is the field for rules
 type field struct {
-	Name   string // field name
-	Remove bool   // whether to remove the field after merged
+	Name   string   // field name, or a dot-separated path of nested fields
+	Remove bool     // whether to remove the field after merged
+	Keys   []string // fields of a composite key, who must match together
+	// Path is the pattern of arrays the rule applies to, nil for all arrays
+	Path *pointer.Pattern
+}
+
+// appliesTo tells if the rule of the field applies to the array at path,
+// where a nil path means the field is not in an element of an array,
+// to which only rules of all arrays apply.
+func (f field) appliesTo(path []string) bool {
+	if f.Path == nil {
+		return true
+	}
+	return path != nil && f.Path.Match(path)
 }
 
 // WithOrderBy is the order by field for slice sort rule
@@ -36,7 +109,14 @@ func WithOrderBy(name string) Option {
 	}
 }
 
-// WithMergeBy is the merge by field for slice sort rule
+// WithMergeBy is the merge by field for slice merge rule.
+//
+// Elements of arrays are merged if they have the same value at name,
+// which is a non-empty string, a number or a boolean. String values
+// also match those of other merge by fields, while numbers and booleans
+// only match those of the same field. The name can
+// be a dot-separated path of nested fields, e.g.: "settings.id",
+// which is used only if the element has no field of the exact name.
 func WithMergeBy(name string) Option {
 	return func(m *Merger) {
 		m.options.MergeBy = append(m.options.MergeBy, field{
@@ -46,6 +126,82 @@ func WithMergeBy(name string) Option {
 	}
 }
 
+// WithMergeByKeys is the composite merge by fields for slice merge rule,
+// where elements are merged if they have the same values at all of the
+// names, e.g.: WithMergeByKeys("protocol", "port"). See WithMergeBy for
+// the valid values and names.
+func WithMergeByKeys(names ...string) Option {
+	return func(m *Merger) {
+		if len(names) == 0 {
+			m.options.setErr(fmt.Errorf("no keys for merge by"))
+			return
+		}
+		m.options.MergeBy = append(m.options.MergeBy, field{
+			Name: strings.Join(names, "+"),
+			Keys: append([]string(nil), names...),
+		})
+	}
+}
+
+// WithOrderByAt is like WithOrderBy, but only applies to the arrays
+// at path, which is a JSON pointer pattern like WithArrayStrategy accepts.
+func WithOrderByAt(path, name string) Option {
+	return withFieldAt(path, &field{Name: name}, false)
+}
+
+// WithOrderByAtAndRemove is like WithOrderByAndRemove, but only applies to
+// the arrays at path, and the field is only removed from their elements.
+func WithOrderByAtAndRemove(path, name string) Option {
+	return withFieldAt(path, &field{Name: name, Remove: true}, false)
+}
+
+// WithMergeByAt is like WithMergeBy, but only applies to the arrays at
+// path, which is a JSON pointer pattern like WithArrayStrategy accepts,
+// e.g.: WithMergeByAt("/outbounds", "tag").
+func WithMergeByAt(path, name string) Option {
+	return withFieldAt(path, &field{Name: name}, true)
+}
+
+// WithMergeByAtAndRemove is like WithMergeByAndRemove, but only applies to
+// the arrays at path, and the field is only removed from their elements.
+func WithMergeByAtAndRemove(path, name string) Option {
+	return withFieldAt(path, &field{Name: name, Remove: true}, true)
+}
+
+// WithMergeByKeysAt is like WithMergeByKeys, but only applies
+// to the arrays at path.
+func WithMergeByKeysAt(path string, names ...string) Option {
+	return func(m *Merger) {
+		if len(names) == 0 {
+			m.options.setErr(fmt.Errorf("no keys for merge by"))
+			return
+		}
+		withFieldAt(path, &field{
+			Name: strings.Join(names, "+"),
+			Keys: append([]string(nil), names...),
+		}, true)(m)
+	}
+}
+
+// withFieldAt adds the field f of the merge by rules if mergeBy,
+// otherwise the order by rules, which applies to the arrays at path.
+func withFieldAt(path string, f *field, mergeBy bool) Option {
+	return func(m *Merger) {
+		pattern, err := pointer.ParsePattern(path)
+		if err != nil {
+			m.options.setErr(err)
+			return
+		}
+		f := *f
+		f.Path = pattern
+		if mergeBy {
+			m.options.MergeBy = append(m.options.MergeBy, f)
+		} else {
+			m.options.OrderBy = append(m.options.OrderBy, f)
+		}
+	}
+}
+
 // WithOrderByAndRemove is the order by field for slice merge rule
 func WithOrderByAndRemove(name string) Option {
 	return func(m *Merger) {
@@ -73,6 +229,83 @@ func WithTypeOverride(override bool) Option {
 	}
 }
 
+// WithMergePatchSemantics makes the merger follow RFC 7396 JSON Merge Patch,
+// where a null value deletes the field, arrays are replaced instead of appended,
+// and objects are merged recursively.
+func WithMergePatchSemantics() Option {
+	return func(m *Merger) {
+		m.options.MergePatch = true
+	}
+}
+
+// ArrayStrategy is the strategy to merge arrays
+type ArrayStrategy = merge.ArrayStrategy
+
+// Array merge strategies
+const (
+	// ArrayAppend appends incoming elements to the existing ones, which is the default
+	ArrayAppend = merge.ArrayAppend
+	// ArrayPrepend inserts incoming elements before the existing ones
+	ArrayPrepend = merge.ArrayPrepend
+	// ArrayReplace replaces the existing array with the incoming one
+	ArrayReplace = merge.ArrayReplace
+	// ArrayMergeByIndex merges elements at the same index
+	ArrayMergeByIndex = merge.ArrayMergeByIndex
+	// ArrayUnion appends incoming elements which are not deeply equal to any existing one
+	ArrayUnion = merge.ArrayUnion
+)
+
+// WithArrayStrategy sets the strategy to merge arrays at path, which is
+// a JSON pointer where "*" matches any single field name or index,
+// and "**" matches any number of them, e.g.:
+//
+//	"/route/rules"
+//	"/inbounds/*/settings/clients"
+//	"/**/rules"
+//
+// If more than one rule matches an array, the last one wins.
+func WithArrayStrategy(path string, strategy ArrayStrategy) Option {
+	return func(m *Merger) {
+		if !strategy.Valid() {
+			m.options.setErr(fmt.Errorf("unknown array strategy: %s", strategy))
+			return
+		}
+		pattern, err := pointer.ParsePattern(path)
+		if err != nil {
+			m.options.setErr(err)
+			return
+		}
+		m.options.ArrayRules = append(m.options.ArrayRules, merge.ArrayRule{
+			Pattern:  pattern,
+			Strategy: strategy,
+		})
+	}
+}
+
+// DefaultDirectivePrefix is the default prefix of in-document merge directives
+const DefaultDirectivePrefix = "$"
+
+// WithDirectives enables in-document merge directives with the prefix,
+// which let a document control how its objects are merged, e.g., with
+// the DefaultDirectivePrefix:
+//
+//	{"dns": {"$replace": true, "servers": []}}   // replace "dns" instead of merging
+//	{"dns": {"$replace": ["1.1.1.1"]}}           // replace "dns" with the value
+//	{"servers": {"$delete": true}}               // delete "servers"
+//	{"rules": {"$append": [{"outbound": "a"}]}}  // append to "rules"
+//	{"rules": {"$prepend": [{"outbound": "a"}]}} // prepend to "rules"
+//
+// Directive fields are removed after merged.
+// If prefix is empty, DefaultDirectivePrefix is used.
+func WithDirectives(prefix string) Option {
+	return func(m *Merger) {
+		if prefix == "" {
+			prefix = DefaultDirectivePrefix
+		}
+		m.options.Directives = prefix
+	}
+}
+
 // WithIndent sets the indent options for merged output.
 func WithIndent(prefix, indent string) Option {
 	return func(m *Merger) {
@@ -81,6 +314,123 @@ func WithIndent(prefix, indent string) Option {
 	}
 }
 
+// WithDisallowUnknownFields makes MergeInto reject fields which are
+// not found in the destination struct, reporting the input who wrote
+// the field as a *MergeError with ErrUnknownField.
+func WithDisallowUnknownFields() Option {
+	return func(m *Merger) {
+		m.options.DisallowUnknownFields = true
+	}
+}
+
+// WithUseNumber makes the JSON loaders decode numbers as json.Number
+// instead of float64, so that big integers like 64-bit IDs keep their
+// precision end to end, including JSON Patch documents, overrides and
+// MergeInto. Numbers of json.Number and float64 are merged, compared
+// and sorted as the same type.
+//
+// Loaders registered by RegisterLoader and RegisterOrderedLoader
+// are not affected.
+func WithUseNumber() Option {
+	return func(m *Merger) {
+		m.options.UseNumber = true
+	}
+}
+
+// LookupFunc looks up the variable named name for interpolation
+type LookupFunc = interpolate.LookupFunc
+
+// WithInterpolation enables interpolation of string values in the merged
+// result, which expands the references below:
+//
+//	${NAME}            the variable NAME
+//	${NAME:-default}   the variable NAME, or default if it's undefined or empty
+//	${/path/to/value}  the value at the JSON pointer of the merged result
+//	$${                the literal "${"
+//
+// Variables are looked up by lookup, or os.LookupEnv if nil. A string
+// which is a single pointer reference is replaced by the referenced value,
+// keeping its type, e.g. "${/inbounds/0/port}" is replaced by the number.
+//
+// Interpolation runs after all inputs are merged and the rules applied.
+// Referencing undefined variables or values is an error, see
+// ErrUndefinedReference and ErrReferenceCycle.
+func WithInterpolation(lookup LookupFunc) Option {
+	return func(m *Merger) {
+		if lookup == nil {
+			lookup = os.LookupEnv
+		}
+		m.options.Lookup = lookup
+	}
+}
+
+// DefaultMaxIncludeDepth is the default max depth of nested includes
+const DefaultMaxIncludeDepth = 10
+
+// WithIncludes enables the include directive, which includes files into
+// the object holding it, e.g.:
+//
+//	{"dns": {"$include": "common/dns.json"}}
+//	{"$include": ["common/log.json", "secrets.yaml"], "log": {"level": "info"}}
+//
+// Included files are resolved relative to the including file, or the
+// current directory of the file system for non-file inputs. They are
+// loaded by the registered loaders, merged in order, and then the own
+// fields of the object are merged on top of them. Directories and glob
+// patterns are accepted like the path inputs of Merge.
+//
+// The directive name is "include" with the prefix of WithDirectives,
+// or DefaultDirectivePrefix if not set. Including a file being included
+// is an error, and so is exceeding maxDepth of nested includes, which
+// is DefaultMaxIncludeDepth if maxDepth <= 0.
+func WithIncludes(maxDepth int) Option {
+	return func(m *Merger) {
+		if maxDepth <= 0 {
+			maxDepth = DefaultMaxIncludeDepth
+		}
+		m.options.Includes = true
+		m.options.MaxIncludeDepth = maxDepth
+	}
+}
+
+// WithMaxInputSize limits the size of each input in bytes, including
+// files, readers and byte slices, and the included files.
+// Readers are never read beyond the limit.
+func WithMaxInputSize(size int64) Option {
+	return func(m *Merger) {
+		m.options.MaxInputSize = size
+	}
+}
+
+// WithMaxDepth limits the nesting depth of objects and arrays in each
+// loaded document, where the root object is at depth 1. The values of
+// JSON Patch operations are at the depth of their target paths.
+func WithMaxDepth(depth int) Option {
+	return func(m *Merger) {
+		m.options.MaxDepth = depth
+	}
+}
+
+// WithMaxKeys limits the total number of object keys
+// in all documents loaded by a merge call.
+func WithMaxKeys(keys int) Option {
+	return func(m *Merger) {
+		m.options.MaxKeys = keys
+	}
+}
+
+// WithFS sets the file system to load files from, e.g. embed.FS or
+// fstest.MapFS, instead of the local one. Paths of inputs are then
+// the unrooted, slash-separated paths of fsys, like "conf.d/a.json".
+//
+// It applies to files, directories and glob patterns of string inputs,
+// and FSPath inputs without their own file systems.
+func WithFS(fsys fs.FS) Option {
+	return func(m *Merger) {
+		m.options.FS = fsys
+	}
+}
+
 // WithPreprocessor adds a preprocessor function to preprocess values before merging.
 func WithPreprocessor(preprocessor PreprocessorFunc) Option {
 	return func(m *Merger) {
@@ -6,81 +6,169 @@ package jsons
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
+	"github.com/qjebbs/go-jsons/internal/merge"
 	"github.com/qjebbs/go-jsons/internal/ordered"
+	"github.com/qjebbs/go-jsons/internal/pointer"
 )
 
 // apply applies rule according to m
-func (r *options) apply(m *ordered.Map) error {
+// tracker tracks the sources of values if not nil.
+func (r *options) apply(m *ordered.Map, tracker *merge.Tracker) error {
 	if r == nil || (len(r.MergeBy) == 0 && len(r.OrderBy) == 0 && len(r.Preprocessors) == 0) {
 		return nil
 	}
-	err := r.sortMergeSlices(m)
+	err := r.sortMergeSlices(r.mergeOptions(tracker), nil, m)
 	if err != nil {
 		return err
 	}
-	r.removeHelperFields(m)
+	r.removeHelperFields(nil, nil, m)
 	return nil
 }
 
 // sortMergeSlices enumerates all slices in a map, to sort by order and merge by tag
-func (r *options) sortMergeSlices(target *ordered.Map) error {
-	for key, value := range target.Values {
+func (r *options) sortMergeSlices(opts *merge.Options, path []string, target *ordered.Map) error {
+	for _, key := range target.Keys {
+		value := target.Values[key]
+		fieldPath := pointer.Append(path, key)
 		for _, pre := range r.Preprocessors {
 			value = pre(key, value)
 		}
 		target.Set(key, value)
 		if slice, ok := value.([]interface{}); ok {
-			sortByFields(slice, r.OrderBy)
-			s, err := mergeByFields(slice, r.MergeBy, r.TypeOverride)
+			order := sortByFields(slice, fieldsAt(r.OrderBy, fieldPath))
+			opts.Tracker.Reorder(target, key, order)
+			s, kept, err := mergeByFields(fieldPath, slice, fieldsAt(r.MergeBy, fieldPath), opts)
 			if err != nil {
 				return err
 			}
+			opts.Tracker.Reorder(target, key, kept)
 			for i, item := range s {
 				for _, pre := range r.Preprocessors {
 					s[i] = pre(fmt.Sprintf("%s[%d]", key, i), item)
 				}
 				if m, ok := item.(*ordered.Map); ok {
-					r.sortMergeSlices(m)
+					err := r.sortMergeSlices(opts, pointer.Append(fieldPath, strconv.Itoa(i)), m)
+					if err != nil {
+						return err
+					}
 				}
 			}
 			target.Set(key, s)
 		} else if field, ok := value.(*ordered.Map); ok {
-			r.sortMergeSlices(field)
+			if err := r.sortMergeSlices(opts, fieldPath, field); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
 
-func (r *options) removeHelperFields(target *ordered.Map) {
+// mergeOptions returns the options for merging maps,
+// tracker tracks the sources of values if not nil.
+func (r *options) mergeOptions(tracker *merge.Tracker) *merge.Options {
+	opts := &merge.Options{
+		Tracker:         tracker,
+		TypeOverride:    r.TypeOverride,
+		MergePatch:      r.MergePatch,
+		ArrayRules:      r.ArrayRules,
+		DirectivePrefix: r.Directives,
+	}
+	if r.Conflicts != nil && tracker != nil {
+		opts.OnConflict = r.Conflicts.onConflict
+	}
+	return opts
+}
+
+// needsTracker tells if the options need a tracker to tell
+// the sources of values, for visitors, schema or conflicts, or
+// the inputs of errors merging elements by the MergeBy rules.
+func (r *options) needsTracker() bool {
+	if r.Schema != nil || r.Conflicts != nil || len(r.MergeBy) > 0 {
+		return true
+	}
+	for _, v := range r.Visitors {
+		if v.Phase != PhaseBeforeMerge {
+			return true
+		}
+	}
+	return false
+}
+
+// removeHelperFields removes the fields of the rules to remove from
+// target at path, where arrayPath is the path of the array holding
+// target, nil if it's not an element of an array.
+func (r *options) removeHelperFields(path, arrayPath []string, target *ordered.Map) {
+	r.removeNestedHelperFields(arrayPath, target)
 	for key, value := range target.Values {
-		if r.shouldDelete(key) {
+		fieldPath := pointer.Append(path, key)
+		if r.shouldDelete(key, arrayPath) {
 			target.Remove(key)
 		} else if slice, ok := value.([]interface{}); ok {
-			for _, e := range slice {
+			for i, e := range slice {
 				if el, ok := e.(*ordered.Map); ok {
-					r.removeHelperFields(el)
+					r.removeHelperFields(pointer.Append(fieldPath, strconv.Itoa(i)), fieldPath, el)
 				}
 			}
 		} else if field, ok := value.(*ordered.Map); ok {
-			r.removeHelperFields(field)
+			r.removeHelperFields(fieldPath, nil, field)
 		}
 	}
 }
 
-// shouldDelete tells if the field should be deleted according to the rules
-func (r *options) shouldDelete(key string) bool {
+// removeNestedHelperFields removes the nested fields of the rules,
+// whose names are dot-separated paths, e.g.: "settings._id".
+func (r *options) removeNestedHelperFields(arrayPath []string, target *ordered.Map) {
+	for _, fields := range [][]field{r.MergeBy, r.OrderBy} {
+		for _, field := range fields {
+			if !field.Remove || !field.appliesTo(arrayPath) ||
+				!strings.Contains(field.Name, ".") || target.Has(field.Name) {
+				continue
+			}
+			if parent, key, ok := lookupParent(target, field.Name); ok {
+				parent.Remove(key)
+			}
+		}
+	}
+}
+
+// shouldDelete tells if the field should be deleted according to the rules,
+// where arrayPath is the path of the array holding the field's object.
+func (r *options) shouldDelete(key string, arrayPath []string) bool {
 	for _, field := range r.MergeBy {
-		if key != field.Name {
+		if key != field.Name || !field.appliesTo(arrayPath) {
 			continue
 		}
 		return field.Remove
 	}
 	for _, field := range r.OrderBy {
-		if key != field.Name {
+		if key != field.Name || !field.appliesTo(arrayPath) {
 			continue
 		}
 		return field.Remove
 	}
 	return false
 }
+
+// fieldsAt returns the fields of the rules applied to the array at path
+func fieldsAt(fields []field, path []string) []field {
+	scoped := false
+	for _, f := range fields {
+		if f.Path != nil {
+			scoped = true
+			break
+		}
+	}
+	if !scoped {
+		return fields
+	}
+	r := make([]field, 0, len(fields))
+	for _, f := range fields {
+		if f.appliesTo(path) {
+			r = append(r, f)
+		}
+	}
+	return r
+}
@@ -0,0 +1,120 @@
+package jsons
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/qjebbs/go-jsons/internal/pointer"
+)
+
+// ErrConflict is the error of a value overwritten by a later input,
+// see WithStrictConflicts.
+var ErrConflict = errors.New("conflicting value")
+
+// Conflict is a value set by an input and overwritten
+// by a different value of a later input.
+type Conflict struct {
+	// Path is the JSON pointer of the value
+	Path string
+	// Value is the overwritten value, set by Input
+	Value interface{}
+	Input string
+	// NewValue is the value of NewInput, who overwrites Value
+	NewValue interface{}
+	NewInput string
+}
+
+// String returns the description of the conflict.
+func (c Conflict) String() string {
+	return fmt.Sprintf(
+		"%s: %s of %s is overwritten by %s of %s",
+		c.Path, marshalValue(c.Value), c.Input, marshalValue(c.NewValue), c.NewInput,
+	)
+}
+
+// conflictRule is the rule of conflicts, see WithStrictConflicts
+type conflictRule struct {
+	// Report reports the conflicts, nil to fail the merge
+	Report func(Conflict)
+	// Allow are the paths allowed to be overwritten
+	Allow []*pointer.Pattern
+}
+
+// WithStrictConflicts makes overwriting a string, number or boolean set
+// by an earlier input with a different value an error, naming both inputs,
+// unless the path matches one of allow, which are JSON pointer patterns
+// like WithArrayStrategy accepts, e.g.:
+//
+//	b.json: /log/level: conflicting value: overwrites "debug" set by a.json
+//
+// Objects and arrays are merged as usual, and the conflicts of their
+// members are checked. Nulls are not checked, which means no value: a null
+// of an earlier input can be overwritten, and a null of a later input
+// keeps or deletes the value without a conflict. Values written by merge
+// directives are not checked.
+func WithStrictConflicts(allow ...string) Option {
+	return withConflictRule(nil, allow)
+}
+
+// WithConflictReporter reports the conflicts like WithStrictConflicts
+// checks to report, e.g. as warnings, instead of failing the merge.
+func WithConflictReporter(report func(Conflict), allow ...string) Option {
+	return func(m *Merger) {
+		if report == nil {
+			m.options.setErr(errors.New("nil conflict reporter"))
+			return
+		}
+		withConflictRule(report, allow)(m)
+	}
+}
+
+func withConflictRule(report func(Conflict), allow []string) Option {
+	return func(m *Merger) {
+		rule := &conflictRule{Report: report}
+		for _, path := range allow {
+			pattern, err := pointer.ParsePattern(path)
+			if err != nil {
+				m.options.setErr(err)
+				return
+			}
+			rule.Allow = append(rule.Allow, pattern)
+		}
+		m.options.Conflicts = rule
+	}
+}
+
+// onConflict handles the conflict of the value at path,
+// see merge.Options.OnConflict.
+func (r *conflictRule) onConflict(path []string, target, source interface{}, prev, cur string) error {
+	for _, p := range r.Allow {
+		if p.Match(path) {
+			return nil
+		}
+	}
+	c := Conflict{
+		Path:     pointer.Format(path),
+		Value:    target,
+		Input:    prev,
+		NewValue: source,
+		NewInput: cur,
+	}
+	if r.Report != nil {
+		r.Report(c)
+		return nil
+	}
+	return &MergeError{
+		Input: cur,
+		Path:  c.Path,
+		Err:   fmt.Errorf("%w: overwrites %s set by %s", ErrConflict, marshalValue(target), prev),
+	}
+}
+
+// marshalValue returns the JSON of v for messages
+func marshalValue(v interface{}) string {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(bs)
+}
@@ -0,0 +1,124 @@
+package jsons_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/qjebbs/go-jsons"
+)
+
+func TestStrictConflicts(t *testing.T) {
+	a := []byte(`{"log":{"level":"debug"},"port":1080,"list":[{"tag":"x","v":1}]}`)
+	b := []byte(`{"log":{"level":"error"}}`)
+	_, err := jsons.NewMerger(jsons.WithStrictConflicts()).Merge(a, b)
+	if !errors.Is(err, jsons.ErrConflict) {
+		t.Fatalf("want ErrConflict, got %v", err)
+	}
+	want := `inputs[1]: /log/level: conflicting value: overwrites "debug" set by inputs[0]`
+	if err.Error() != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, err)
+	}
+
+	testCases := []struct {
+		name   string
+		option jsons.Option
+		inputs []interface{}
+	}{
+		{
+			name:   "allowed",
+			option: jsons.WithStrictConflicts("/log/level"),
+			inputs: []interface{}{a, b},
+		},
+		{
+			name:   "allowed by pattern",
+			option: jsons.WithStrictConflicts("/**/level"),
+			inputs: []interface{}{a, b},
+		},
+		{
+			name:   "same value",
+			option: jsons.WithStrictConflicts(),
+			inputs: []interface{}{a, []byte(`{"port":1080,"log":{"level":"debug"}}`)},
+		},
+		{
+			name:   "new fields",
+			option: jsons.WithStrictConflicts(),
+			inputs: []interface{}{a, []byte(`{"log":{"file":"a.log"},"list":[{"tag":"y","v":2}]}`)},
+		},
+		{
+			name:   "null",
+			option: jsons.WithStrictConflicts(),
+			inputs: []interface{}{[]byte(`{"n":null,"port":1080}`), []byte(`{"n":1,"port":null}`)},
+		},
+		{
+			name:   "same input",
+			option: jsons.WithStrictConflicts(),
+			inputs: []interface{}{[]byte(`{"list":[{"tag":"x","v":1},{"tag":"x","v":2}]}`)},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := jsons.NewMerger(tc.option, jsons.WithMergeBy("tag"))
+			if _, err := m.Merge(tc.inputs...); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	// nulls delete values in merge patch semantics
+	got, err := jsons.NewMerger(jsons.WithStrictConflicts(), jsons.WithMergePatchSemantics()).Merge(
+		[]byte(`{"port":1080,"log":{"level":"debug"}}`), []byte(`{"port":null,"log":null}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != `{}` {
+		t.Errorf("want {}, got %s", got)
+	}
+
+	// elements merged by tag
+	_, err = jsons.NewMerger(jsons.WithStrictConflicts(), jsons.WithMergeBy("tag")).Merge(
+		a, []byte(`{"list":[{"tag":"x","v":2}]}`),
+	)
+	want = `inputs[1]: /list/0/v: conflicting value: overwrites 1 set by inputs[0]`
+	if err == nil || err.Error() != want {
+		t.Errorf("want:\n%s\ngot:\n%v", want, err)
+	}
+	// elements of nested arrays merged by tag
+	got, err = jsons.NewMerger(jsons.WithStrictConflicts(), jsons.WithMergeBy("tag")).Merge(
+		[]byte(`{"route":{"rules":[{"tag":"x","level":"a"}]}}`),
+		[]byte(`{"route":{"rules":[{"tag":"x","level":"b"}]}}`),
+	)
+	want = `inputs[1]: /route/rules/0/level: conflicting value: overwrites "a" set by inputs[0]`
+	if err == nil || err.Error() != want {
+		t.Errorf("want:\n%s\ngot:\n%v (%s)", want, err, got)
+	}
+}
+
+func TestConflictReporter(t *testing.T) {
+	var got []string
+	m := jsons.NewMerger(
+		jsons.WithConflictReporter(func(c jsons.Conflict) {
+			got = append(got, c.String())
+		}, "/port"),
+		jsons.WithTypeOverride(true),
+	)
+	merged, err := m.Merge(
+		[]byte(`{"log":{"level":"debug"},"port":1080,"n":null}`),
+		[]byte(`{"log":{"level":"error"},"port":1081,"n":1}`),
+		[]byte(`{"log":{"level":1}}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"log":{"level":1},"port":1081,"n":1}`; string(merged) != want {
+		t.Errorf("want %s, got %s", want, merged)
+	}
+	want := []string{
+		`/log/level: "debug" of inputs[0] is overwritten by "error" of inputs[1]`,
+		`/log/level: "error" of inputs[1] is overwritten by 1 of inputs[2]`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want:\n%v\ngot:\n%v", want, got)
+	}
+}
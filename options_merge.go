@@ -5,18 +5,34 @@
 package jsons
 
 import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+
 	"github.com/qjebbs/go-jsons/internal/merge"
 	"github.com/qjebbs/go-jsons/internal/ordered"
+	"github.com/qjebbs/go-jsons/internal/pointer"
 )
 
-func mergeByFields(s []interface{}, fields []field, typeOverride bool) ([]interface{}, error) {
+// mergeByFields merges elements with same tags, and returns the merged slice
+// and the original indexes of its elements, which is nil if nothing merged.
+// The slice s is not modified, while the elements merged into are.
+func mergeByFields(path []string, s []interface{}, fields []field, opts *merge.Options) ([]interface{}, []int, error) {
 	if len(s) == 0 || len(fields) == 0 {
-		return s, nil
+		return s, nil, nil
 	}
-	// from: [a,"",b,"",a,"",b,""]
-	// to: [a,"",b,"",merged,"",merged,""]
-	merged := &struct{}{}
+	// from: [a,b,a,c]
+	// to: [a+a,b,c]
+	merged := make([]bool, len(s))
+	ns := make([]interface{}, 0, len(s))
+	kept := make([]int, 0, len(s))
 	for i, item1 := range s {
+		if merged[i] {
+			continue
+		}
+		ns = append(ns, item1)
+		kept = append(kept, i)
 		map1, ok := item1.(*ordered.Map)
 		if !ok {
 			continue
@@ -27,29 +43,25 @@ func mergeByFields(s []interface{}, fields []field, typeOverride bool) ([]interf
 		}
 		for j := i + 1; j < len(s); j++ {
 			map2, ok := s[j].(*ordered.Map)
-			if !ok {
+			if !ok || merged[j] {
 				continue
 			}
 			tags2 := getTags(map2, fields)
 			if !matchTags(tags1, tags2) {
 				continue
 			}
-			s[j] = merged
-			err := merge.OrderedMaps(map1, []*ordered.Map{map2}, typeOverride)
+			merged[j] = true
+			// the index of map1 in the merged slice
+			err := opts.OrderedMapAt(pointer.Append(path, strconv.Itoa(len(ns)-1)), map1, map2)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 		}
 	}
-	// remove merged
-	ns := make([]interface{}, 0)
-	for _, item := range s {
-		if item == merged {
-			continue
-		}
-		ns = append(ns, item)
+	if len(ns) == len(s) {
+		return s, nil, nil
 	}
-	return ns, nil
+	return ns, kept, nil
 }
 
 func matchTags(a, b []string) bool {
@@ -63,16 +75,107 @@ func matchTags(a, b []string) bool {
 	return false
 }
 
+// getTags returns the tags of v by the fields, where the tag of a field
+// is the value at its name, or the values at its keys together if it's
+// a composite one, see tagOf for the valid values.
+//
+// String tags match those of other fields, e.g.: "tag" and "_tag", while
+// the tags of numbers, booleans and composite fields are prefixed with the
+// field name, so that they only match those of the same field.
 func getTags(v *ordered.Map, fields []field) []string {
 	tags := make([]string, 0, len(fields))
 	for _, field := range fields {
-		value, ok := v.Values[field.Name]
-		if !ok {
+		if len(field.Keys) == 0 {
+			tag, ok := tagOf(lookupField(v, field.Name))
+			if !ok {
+				continue
+			}
+			if !strings.HasPrefix(tag, `"`) {
+				tag = field.Name + "=" + tag
+			}
+			tags = append(tags, tag)
 			continue
 		}
-		if tag, ok := value.(string); ok && tag != "" {
-			tags = append(tags, tag)
+		parts := make([]string, 0, len(field.Keys))
+		for _, key := range field.Keys {
+			tag, ok := tagOf(lookupField(v, key))
+			if !ok {
+				break
+			}
+			parts = append(parts, tag)
+		}
+		if len(parts) == len(field.Keys) {
+			tags = append(tags, field.Name+"=["+strings.Join(parts, ",")+"]")
 		}
 	}
 	return tags
 }
+
+// tagOf returns the tag of the value v, which is valid if v is
+// a non-empty string, a number or a boolean. Tags of different
+// types never match, e.g.: 1 and "1".
+func tagOf(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		if v == "" {
+			return "", false
+		}
+		return strconv.Quote(v), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return strconv.FormatInt(i, 10), true
+		}
+		if f, err := v.Float64(); err == nil {
+			return strconv.FormatFloat(f, 'g', -1, 64), true
+		}
+		return v.String(), true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 64), true
+	default:
+		return "", false
+	}
+}
+
+// lookupField returns the value of the field name in v, where name is
+// the key of v if exists, otherwise a dot-separated path of nested
+// objects, e.g.: "settings.id".
+func lookupField(v *ordered.Map, name string) interface{} {
+	if value, ok := v.Values[name]; ok {
+		return value
+	}
+	parent, key, ok := lookupParent(v, name)
+	if !ok {
+		return nil
+	}
+	return parent.Values[key]
+}
+
+// lookupParent returns the object holding the nested field of
+// the dot-separated path name in v, and the key of it.
+func lookupParent(v *ordered.Map, name string) (*ordered.Map, string, bool) {
+	tokens := strings.Split(name, ".")
+	if len(tokens) < 2 {
+		return nil, "", false
+	}
+	for _, token := range tokens[:len(tokens)-1] {
+		child, ok := v.Values[token].(*ordered.Map)
+		if !ok {
+			return nil, "", false
+		}
+		v = child
+	}
+	key := tokens[len(tokens)-1]
+	if _, ok := v.Values[key]; !ok {
+		return nil, "", false
+	}
+	return v, key, true
+}
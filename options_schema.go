@@ -0,0 +1,109 @@
+package jsons
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/qjebbs/go-jsons/internal/merge"
+	"github.com/qjebbs/go-jsons/internal/ordered"
+	"github.com/qjebbs/go-jsons/internal/pointer"
+	"github.com/qjebbs/go-jsons/internal/schema"
+)
+
+// ErrSchemaViolation is the error of merged results violating
+// the schema, see WithSchema.
+var ErrSchemaViolation = errors.New("schema violation")
+
+// SchemaError is the error of a merged result violating the schema,
+// which lists every violation.
+type SchemaError struct {
+	// Violations are the violations in document order, where Input is
+	// the input who introduced the value if known, and Err wraps
+	// ErrSchemaViolation.
+	Violations []*MergeError
+}
+
+// Error implements the error interface.
+func (e *SchemaError) Error() string {
+	var b strings.Builder
+	b.WriteString("merged result violates the schema:")
+	for _, v := range e.Violations {
+		b.WriteString("\n\t")
+		b.WriteString(v.Error())
+	}
+	return b.String()
+}
+
+// Unwrap returns ErrSchemaViolation.
+func (e *SchemaError) Unwrap() error {
+	return ErrSchemaViolation
+}
+
+// WithSchema validates merged results against the JSON Schema, which
+// supports a subset of draft 2020-12:
+//
+//	type, enum, const
+//	properties, required, patternProperties, additionalProperties
+//	items, minItems, maxItems
+//	pattern, minLength, maxLength
+//	minimum, maximum, exclusiveMinimum, exclusiveMaximum
+//	$ref within the schema, $defs
+//
+// Other keywords are ignored. The merge fails with a *SchemaError
+// listing every violation if the result is invalid.
+func WithSchema(schemaJSON []byte) Option {
+	return func(m *Merger) {
+		s, err := schema.Compile(schemaJSON)
+		if err != nil {
+			m.options.setErr(err)
+			return
+		}
+		m.options.Schema = s
+	}
+}
+
+// validate validates the merged target against the schema,
+// with the sources of values tracked by tracker.
+func (r *options) validate(target *ordered.Map, tracker *merge.Tracker) error {
+	if r.Schema == nil {
+		return nil
+	}
+	violations := r.Schema.Validate(target)
+	if len(violations) == 0 {
+		return nil
+	}
+	prov := tracker.Provenance(target)
+	err := &SchemaError{}
+	for _, v := range violations {
+		err.Violations = append(err.Violations, &MergeError{
+			Input: sourceAt(prov, v.Path),
+			Path:  pointer.Format(v.Path),
+			Err:   &schemaViolation{v.Msg},
+		})
+	}
+	return err
+}
+
+// sourceAt returns the source of the value at path, or the
+// nearest ancestor whose source is known.
+func sourceAt(prov map[string]string, path []string) string {
+	for i := len(path); i > 0; i-- {
+		if source, ok := prov[pointer.Format(path[:i])]; ok {
+			return source
+		}
+	}
+	return ""
+}
+
+// schemaViolation is a violation of the schema, which wraps ErrSchemaViolation
+type schemaViolation struct {
+	msg string
+}
+
+func (e *schemaViolation) Error() string {
+	return e.msg
+}
+
+func (e *schemaViolation) Unwrap() error {
+	return ErrSchemaViolation
+}
@@ -0,0 +1,67 @@
+package jsons_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/qjebbs/go-jsons"
+)
+
+func TestMergeWithSchema(t *testing.T) {
+	schema := []byte(`{
+		"type": "object",
+		"required": ["log"],
+		"properties": {
+			"log": {"type": "object", "properties": {"level": {"enum": ["debug", "info"]}}},
+			"inbounds": {"items": {"properties": {"port": {"type": "integer"}}, "required": ["tag"]}}
+		}
+	}`)
+	m := jsons.NewMerger(jsons.WithSchema(schema))
+	_, err := m.Merge(
+		[]byte(`{"log":{"level":"info"},"inbounds":[{"tag":"a","port":1080}]}`),
+		[]byte(`{"log":{"level":"trace"},"inbounds":[{"port":"x"}]}`),
+	)
+	if !errors.Is(err, jsons.ErrSchemaViolation) {
+		t.Fatalf("want ErrSchemaViolation, got %v", err)
+	}
+	var se *jsons.SchemaError
+	if !errors.As(err, &se) {
+		t.Fatalf("want *SchemaError, got %T", err)
+	}
+	var got []string
+	for _, v := range se.Violations {
+		if !errors.Is(v, jsons.ErrSchemaViolation) {
+			t.Errorf("want violation wraps ErrSchemaViolation: %v", v)
+		}
+		got = append(got, v.Error())
+	}
+	want := []string{
+		`inputs[1]: /log/level: value "trace" is not one of ["debug","info"]`,
+		`inputs[1]: /inbounds/1: missing required property "tag"`,
+		`inputs[1]: /inbounds/1/port: expect integer, got string`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+
+	got2, err := m.Merge([]byte(`{"log":{"level":"debug"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"log":{"level":"debug"}}`; string(got2) != want {
+		t.Errorf("want %s, got %s", want, got2)
+	}
+	// root violations are not related to any input
+	_, err = m.Merge([]byte(`{}`))
+	if !errors.As(err, &se) || len(se.Violations) != 1 || se.Violations[0].Input != "" {
+		t.Errorf("want a violation without input, got %v", err)
+	}
+}
+
+func TestMergeWithInvalidSchema(t *testing.T) {
+	_, err := jsons.NewMerger(jsons.WithSchema([]byte(`{"type":1}`))).Merge([]byte(`{}`))
+	if err == nil {
+		t.Fatal("want error of invalid schema")
+	}
+}
@@ -5,6 +5,7 @@
 package jsons
 
 import (
+	"encoding/json"
 	"math"
 	"sort"
 
@@ -17,10 +18,12 @@ type meta struct {
 	value interface{}
 }
 
-// sortByFields sort slice elements by specified fields
-func sortByFields(slice []interface{}, fields []field) {
+// sortByFields sort slice elements by specified fields,
+// and returns the original indexes of the sorted elements,
+// which is nil if not sorted.
+func sortByFields(slice []interface{}, fields []field) []int {
 	if len(slice) == 0 || len(fields) == 0 {
-		return
+		return nil
 	}
 	metas := make([]meta, len(slice))
 	for i, v := range slice {
@@ -39,9 +42,12 @@ func sortByFields(slice []interface{}, fields []field) {
 			return metas[i].index < metas[j].index
 		},
 	)
+	order := make([]int, len(metas))
 	for i, m := range metas {
 		slice[i] = m.value
+		order[i] = m.index
 	}
+	return order
 }
 
 func getOrder(v interface{}, fields []field) float64 {
@@ -52,8 +58,8 @@ func getOrder(v interface{}, fields []field) float64 {
 	hasField := false
 	min := math.Inf(1)
 	for _, field := range fields {
-		value, ok := m.Values[field.Name]
-		if !ok || value == nil {
+		value := lookupField(m, field.Name)
+		if value == nil {
 			continue
 		}
 		hasField = true
@@ -61,6 +67,8 @@ func getOrder(v interface{}, fields []field) float64 {
 		switch v := value.(type) {
 		case float64:
 			num = v
+		case json.Number:
+			num, _ = v.Float64()
 		case float32:
 			num = float64(v)
 		case int:
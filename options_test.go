@@ -1,6 +1,7 @@
 package jsons
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 
@@ -17,6 +18,7 @@ func TestRules(t *testing.T) {
 	t.Parallel()
 	testCases := []struct {
 		name    string
+		options []Option
 		value   map[string]interface{}
 		want    map[string]interface{}
 		wantErr bool
@@ -106,6 +108,22 @@ func TestRules(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:    "multi_field_no_cross_merge",
+			options: []Option{WithMergeBy("id"), WithMergeBy("port")},
+			value: map[string]interface{}{
+				"a": []interface{}{
+					map[string]interface{}{"id": 1, "x": "A"},
+					map[string]interface{}{"port": 1, "y": "B"},
+				},
+			},
+			want: map[string]interface{}{
+				"a": []interface{}{
+					map[string]interface{}{"id": 1, "x": "A"},
+					map[string]interface{}{"port": 1, "y": "B"},
+				},
+			},
+		},
 		{
 			name: "as_is",
 			value: map[string]interface{}{
@@ -141,18 +159,45 @@ func TestRules(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "number_bool_tag",
+			value: map[string]interface{}{
+				"a": []interface{}{
+					map[string]interface{}{"tag": float64(1), "value": 0},
+					map[string]interface{}{"tag": float64(1), "value": 1},
+					map[string]interface{}{"tag": "1", "value": 2},
+					map[string]interface{}{"tag": true, "value": 3},
+					map[string]interface{}{"tag": true, "value": 4},
+				},
+			},
+			want: map[string]interface{}{
+				"a": []interface{}{
+					map[string]interface{}{"tag": float64(1), "value": 1},
+					map[string]interface{}{"tag": "1", "value": 2},
+					map[string]interface{}{"tag": true, "value": 4},
+				},
+			},
+		},
 		{
 			name: "invalid_tag",
 			value: map[string]interface{}{
 				"a": []interface{}{
-					map[string]interface{}{"tag": 1, "value": 0},
-					map[string]interface{}{"tag": 1, "value": 1},
+					map[string]interface{}{"tag": "", "value": 0},
+					map[string]interface{}{"tag": "", "value": 1},
+					map[string]interface{}{"tag": nil, "value": 2},
+					map[string]interface{}{"tag": nil, "value": 3},
+					map[string]interface{}{"tag": []interface{}{}, "value": 4},
+					map[string]interface{}{"tag": []interface{}{}, "value": 5},
 				},
 			},
 			want: map[string]interface{}{
 				"a": []interface{}{
-					map[string]interface{}{"tag": 1, "value": 0},
-					map[string]interface{}{"tag": 1, "value": 1},
+					map[string]interface{}{"tag": "", "value": 0},
+					map[string]interface{}{"tag": "", "value": 1},
+					map[string]interface{}{"tag": nil, "value": 2},
+					map[string]interface{}{"tag": nil, "value": 3},
+					map[string]interface{}{"tag": []interface{}{}, "value": 4},
+					map[string]interface{}{"tag": []interface{}{}, "value": 5},
 				},
 			},
 		},
@@ -175,6 +220,18 @@ func TestRules(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "nested_merge_fail",
+			value: map[string]interface{}{
+				"a": map[string]interface{}{
+					"b": []interface{}{
+						map[string]interface{}{"tag": "a", "value": 1},
+						map[string]interface{}{"tag": "a", "value": "1"},
+					},
+				},
+			},
+			wantErr: true,
+		},
 	}
 	for _, tc := range testCases {
 		tc := tc
@@ -182,7 +239,11 @@ func TestRules(t *testing.T) {
 			t.Parallel()
 			got := ordered.FromMap(tc.value)
 			want := ordered.FromMap(tc.want)
-			err := m.options.apply(got)
+			m := m
+			if tc.options != nil {
+				m = NewMerger(tc.options...)
+			}
+			err := m.options.apply(got, nil)
 			want.Sort()
 			got.Sort()
 			switch tc.wantErr {
@@ -190,6 +251,12 @@ func TestRules(t *testing.T) {
 				if err == nil {
 					t.Fatal("want err got nil")
 				}
+				// arrays are not changed on failure
+				want := ordered.FromMap(tc.value)
+				want.Sort()
+				if !reflect.DeepEqual(want, got) {
+					t.Fatalf("want:\n%v\n\ngot:\n%v", want, got)
+				}
 			case false:
 				if err != nil {
 					t.Fatal(err)
@@ -204,13 +271,35 @@ func TestRules(t *testing.T) {
 
 func TestNils(t *testing.T) {
 	t.Parallel()
-	err := (*options)(nil).apply(nil)
+	err := (*options)(nil).apply(nil, nil)
 	if err != nil {
 		t.Fatalf("want nil, got err: %s", err)
 	}
 	testRule := &options{}
-	err = testRule.apply(nil)
+	err = testRule.apply(nil, nil)
 	if err != nil {
 		t.Fatalf("want nil, got err: %s", err)
 	}
 }
+
+func TestRulesScopedError(t *testing.T) {
+	m := NewMerger(WithMergeByAt("/route/rules", "tag"))
+	value := map[string]interface{}{
+		"route": map[string]interface{}{
+			"rules": []interface{}{
+				map[string]interface{}{"tag": "x", "v": 1},
+				map[string]interface{}{"tag": "x", "v": "a"},
+			},
+		},
+	}
+	got := ordered.FromMap(value)
+	err := m.options.apply(got, nil)
+	if !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("want ErrTypeMismatch, got %v", err)
+	}
+	// the array is not changed
+	want := ordered.FromMap(value)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want:\n%v\n\ngot:\n%v", want, got)
+	}
+}
@@ -0,0 +1,233 @@
+package jsons
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/qjebbs/go-jsons/internal/merge"
+	"github.com/qjebbs/go-jsons/internal/ordered"
+	"github.com/qjebbs/go-jsons/internal/pointer"
+)
+
+// Phase is the phase of merging when visitors run, see WithVisitor
+type Phase int
+
+// Phases of merging
+const (
+	// PhaseBeforeMerge visits each loaded document before it's merged,
+	// where the includes are resolved. JSON Patch documents are not visited.
+	PhaseBeforeMerge Phase = iota
+	// PhaseAfterMerge visits the merged result, before sorting by
+	// WithOrderBy and merging by WithMergeBy.
+	PhaseAfterMerge
+	// PhaseAfterApply visits the final result, after sorting, merging
+	// by fields and interpolation.
+	PhaseAfterApply
+)
+
+// String returns the name of the phase.
+func (p Phase) String() string {
+	switch p {
+	case PhaseBeforeMerge:
+		return "before-merge"
+	case PhaseAfterMerge:
+		return "after-merge"
+	case PhaseAfterApply:
+		return "after-apply"
+	default:
+		return "phase(" + strconv.Itoa(int(p)) + ")"
+	}
+}
+
+// Visit describes the value being visited
+type Visit struct {
+	Phase Phase
+	// Path is the path of the value from the root of the document
+	Path []string
+	// Parent is the *OrderedMap or []interface{} holding the value,
+	// and Key is the key or index of the value in Parent.
+	Parent interface{}
+	Key    string
+	// Source is the input of the document in PhaseBeforeMerge, or the
+	// input which last wrote the value in other phases, which is empty
+	// for objects written by several inputs.
+	Source string
+}
+
+// Pointer returns the JSON pointer of the value, e.g.: "/inbounds/0/port".
+func (v *Visit) Pointer() string {
+	return pointer.Format(v.Path)
+}
+
+// VisitorFunc visits a value and returns the value to replace it,
+// the children of the returned value are visited afterward.
+//
+// Returning a value only replaces the value in place. To remove a field,
+// call Remove of the Parent *OrderedMap, then the returned value is
+// ignored and its children are not visited. Array elements can't be
+// removed by their visitors, return a new array from the visitor of
+// the array instead.
+type VisitorFunc func(v *Visit, value interface{}) (interface{}, error)
+
+// visitor is a visitor registered by WithVisitor
+type visitor struct {
+	Phase   Phase
+	Pattern *pointer.Pattern
+	Func    VisitorFunc
+}
+
+// WithVisitor adds a visitor to visit the values at path in the phase,
+// where path is a JSON pointer pattern like WithArrayStrategy accepts,
+// and "/**" visits all values except the root, e.g.:
+//
+//	jsons.WithVisitor(jsons.PhaseBeforeMerge, "/inbounds/*/port", normalizePort)
+//
+// Values are visited in document order, parents before children.
+// Errors returned by visitors are reported as *MergeError.
+func WithVisitor(phase Phase, path string, fn VisitorFunc) Option {
+	return func(m *Merger) {
+		if phase < PhaseBeforeMerge || phase > PhaseAfterApply {
+			m.options.setErr(fmt.Errorf("unknown visitor phase: %s", phase))
+			return
+		}
+		if fn == nil {
+			m.options.setErr(fmt.Errorf("nil visitor at %s", path))
+			return
+		}
+		pattern, err := pointer.ParsePattern(path)
+		if err != nil {
+			m.options.setErr(err)
+			return
+		}
+		m.options.Visitors = append(m.options.Visitors, visitor{
+			Phase:   phase,
+			Pattern: pattern,
+			Func:    fn,
+		})
+	}
+}
+
+// visitors returns the visitors of the phase
+func (r *options) visitors(phase Phase) []visitor {
+	var vs []visitor
+	for _, v := range r.Visitors {
+		if v.Phase == phase {
+			vs = append(vs, v)
+		}
+	}
+	return vs
+}
+
+// visitDocument visits the document doc in PhaseBeforeMerge
+func (r *options) visitDocument(doc *document) error {
+	vs := r.visitors(PhaseBeforeMerge)
+	if len(vs) == 0 {
+		return nil
+	}
+	w := &visitWalker{
+		phase:    PhaseBeforeMerge,
+		visitors: vs,
+		source:   func([]string) string { return doc.Source },
+	}
+	return w.walkMap(nil, doc.Map)
+}
+
+// visitMerged visits the merged target in the phase,
+// with the sources of values tracked by tracker.
+func (r *options) visitMerged(phase Phase, target *ordered.Map, tracker *merge.Tracker) error {
+	vs := r.visitors(phase)
+	if len(vs) == 0 {
+		return nil
+	}
+	prov := tracker.Provenance(target)
+	w := &visitWalker{
+		phase:    phase,
+		visitors: vs,
+		source: func(path []string) string {
+			return prov[pointer.Format(path)]
+		},
+	}
+	return w.walkMap(nil, target)
+}
+
+// visitWalker walks values and calls the visitors
+type visitWalker struct {
+	phase    Phase
+	visitors []visitor
+	// source returns the source of the value at path
+	source func(path []string) string
+}
+
+// walkMap walks the fields of m at path
+func (w *visitWalker) walkMap(path []string, m *ordered.Map) error {
+	keys := make([]string, len(m.Keys))
+	copy(keys, m.Keys)
+	for _, k := range keys {
+		value, ok := m.Values[k]
+		if !ok {
+			// removed by visitors
+			continue
+		}
+		value, err := w.walk(pointer.Append(path, k), m, k, value)
+		if err != nil {
+			return err
+		}
+		if removed(m, k) {
+			continue
+		}
+		m.Values[k] = value
+	}
+	return nil
+}
+
+// removed tells if the field key of parent is removed by visitors
+func removed(parent interface{}, key string) bool {
+	m, ok := parent.(*ordered.Map)
+	if !ok {
+		return false
+	}
+	_, ok = m.Values[key]
+	return !ok
+}
+
+// walk visits the value at path held by parent, then its children,
+// and returns the value to replace it.
+func (w *visitWalker) walk(path []string, parent interface{}, key string, value interface{}) (interface{}, error) {
+	for _, v := range w.visitors {
+		if !v.Pattern.Match(path) {
+			continue
+		}
+		source := w.source(path)
+		visit := &Visit{
+			Phase:  w.phase,
+			Path:   path,
+			Parent: parent,
+			Key:    key,
+			Source: source,
+		}
+		var err error
+		value, err = v.Func(visit, value)
+		if err != nil {
+			return nil, &MergeError{Input: source, Path: pointer.Format(path), Err: err}
+		}
+		if removed(parent, key) {
+			return nil, nil
+		}
+	}
+	switch v := value.(type) {
+	case *ordered.Map:
+		if err := w.walkMap(path, v); err != nil {
+			return nil, err
+		}
+	case []interface{}:
+		for i, e := range v {
+			index := strconv.Itoa(i)
+			e, err := w.walk(pointer.Append(path, index), v, index, e)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = e
+		}
+	}
+	return value, nil
+}
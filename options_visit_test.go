@@ -0,0 +1,146 @@
+package jsons_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/qjebbs/go-jsons"
+)
+
+func TestVisitorPaths(t *testing.T) {
+	// ports of inbounds are strings in a.json, but not the port of dns
+	a := []byte(`{"inbounds":[{"tag":"in","port":"1080"}],"dns":{"port":"53"}}`)
+	b := []byte(`{"inbounds":[{"tag":"in","listen":"127.0.0.1"}]}`)
+	m := jsons.NewMerger(
+		jsons.WithMergeBy("tag"),
+		jsons.WithVisitor(jsons.PhaseBeforeMerge, "/inbounds/*/port", func(v *jsons.Visit, value interface{}) (interface{}, error) {
+			var port int
+			_, err := fmt.Sscan(value.(string), &port)
+			return port, err
+		}),
+	)
+	got, err := m.Merge(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"inbounds":[{"tag":"in","port":1080,"listen":"127.0.0.1"}],"dns":{"port":"53"}}`
+	if string(got) != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestVisitorPhases(t *testing.T) {
+	a := []byte(`{"list":[{"tag":"x","v":1}]}`)
+	b := []byte(`{"list":[{"tag":"x","v":2}]}`)
+	var visits []string
+	record := func(v *jsons.Visit, value interface{}) (interface{}, error) {
+		visits = append(visits, fmt.Sprintf("%s %s %s", v.Phase, v.Pointer(), v.Source))
+		return value, nil
+	}
+	m := jsons.NewMerger(
+		jsons.WithMergeBy("tag"),
+		jsons.WithVisitor(jsons.PhaseAfterApply, "/list/*/v", record),
+		jsons.WithVisitor(jsons.PhaseAfterMerge, "/list/*/v", record),
+		jsons.WithVisitor(jsons.PhaseBeforeMerge, "/list/*/v", record),
+	)
+	if _, err := m.Merge(a, b); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"before-merge /list/0/v inputs[0]",
+		"before-merge /list/0/v inputs[1]",
+		"after-merge /list/0/v inputs[0]",
+		"after-merge /list/1/v inputs[1]",
+		"after-apply /list/0/v inputs[1]",
+	}
+	if !reflect.DeepEqual(visits, want) {
+		t.Errorf("want:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(visits, "\n"))
+	}
+}
+
+func TestVisitorParent(t *testing.T) {
+	m := jsons.NewMerger(
+		jsons.WithVisitor(jsons.PhaseAfterMerge, "/**", func(v *jsons.Visit, value interface{}) (interface{}, error) {
+			if v.Key == "secret" {
+				v.Parent.(*jsons.OrderedMap).Remove("secret")
+				return nil, nil
+			}
+			if s, ok := value.(string); ok && v.Key == "1" {
+				if _, ok := v.Parent.([]interface{}); !ok {
+					t.Errorf("want parent array, got %T", v.Parent)
+				}
+				return strings.ToUpper(s), nil
+			}
+			return value, nil
+		}),
+	)
+	got, err := m.Merge([]byte(`{"a":{"secret":1,"b":["x","y"]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"a":{"b":["x","Y"]}}`; string(got) != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
+
+func TestVisitorRemove(t *testing.T) {
+	var visits []string
+	m := jsons.NewMerger(
+		jsons.WithVisitor(jsons.PhaseBeforeMerge, "/**", func(v *jsons.Visit, value interface{}) (interface{}, error) {
+			visits = append(visits, v.Pointer())
+			parent, ok := v.Parent.(*jsons.OrderedMap)
+			if !ok {
+				return value, nil
+			}
+			if v.Key == "secret" || v.Key == "token" {
+				parent.Remove(v.Key)
+				// ignored for the removed field
+				return value, nil
+			}
+			if _, ok := parent.Values["secret"]; ok && v.Key == "b" {
+				t.Errorf("want secret removed from the parent, got %v", parent.Values)
+			}
+			return value, nil
+		}),
+	)
+	a := []byte(`{"a":{"secret":{"x":1},"b":1,"token":"t"},"c":[{"secret":1}]}`)
+	b := []byte(`{"a":{"secret":2}}`)
+	got, err := m.Merge(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"a":{"b":1},"c":[{}]}`; string(got) != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+	want := []string{"/a", "/a/secret", "/a/b", "/a/token", "/c", "/c/0", "/c/0/secret", "/a", "/a/secret"}
+	if strings.Join(visits, " ") != strings.Join(want, " ") {
+		t.Errorf("want visits:\n%v\ngot:\n%v", want, visits)
+	}
+}
+
+func TestVisitorError(t *testing.T) {
+	errInvalid := errors.New("invalid port")
+	m := jsons.NewMerger(
+		jsons.WithVisitor(jsons.PhaseAfterApply, "/**/port", func(v *jsons.Visit, value interface{}) (interface{}, error) {
+			if _, ok := value.(float64); !ok {
+				return nil, errInvalid
+			}
+			return value, nil
+		}),
+	)
+	_, err := m.Merge([]byte(`{"dns":{"port":53}}`), []byte(`{"api":{"port":"x"}}`))
+	if !errors.Is(err, errInvalid) {
+		t.Fatalf("want %v, got %v", errInvalid, err)
+	}
+	var me *jsons.MergeError
+	if !errors.As(err, &me) || me.Input != "inputs[1]" || me.Path != "/api/port" {
+		t.Errorf("want error at inputs[1] /api/port, got %v", err)
+	}
+	_, err = jsons.NewMerger(jsons.WithVisitor(jsons.Phase(9), "/**", nil)).Merge([]byte(`{}`))
+	if err == nil {
+		t.Error("want error of invalid phase")
+	}
+}
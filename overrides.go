@@ -0,0 +1,276 @@
+package jsons
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/qjebbs/go-jsons/internal/ordered"
+	"github.com/qjebbs/go-jsons/internal/patch"
+	"github.com/qjebbs/go-jsons/internal/pointer"
+)
+
+// Overrides is a layer of values set at paths of the merged result,
+// see ParseOverrides and EnvOverrides.
+type Overrides struct {
+	values []overrideValue
+}
+
+// overrideValue is a value set at path
+type overrideValue struct {
+	Path []overrideKey
+	// Value is the raw value, parsed when merged
+	Value string
+}
+
+// ParseOverrides parses "key.path=value" pairs, e.g. from command-line
+// flags, into a layer which can be merged on top of other inputs:
+//
+//	log.level=debug           sets "level" of "log" to "debug"
+//	inbounds[0].port=1080     sets "port" of the 1st element of "inbounds"
+//	dns.servers=["1.1.1.1"]   sets "servers" of "dns" to ["1.1.1.1"]
+//
+// Keys are separated by ".", and indexes "[i]" follow the keys of arrays,
+// where a key is never empty. Values are JSON literals (numbers, booleans,
+// null, strings, arrays and objects) if valid, otherwise strings. Later
+// pairs override the earlier ones.
+//
+// When merged, each value replaces the one at its path, and the missing
+// objects on the path are created. An index "[i]" refers to the existing
+// i-th element of the array, or appends one if i is the length of it:
+//
+//	layer, err := jsons.ParseOverrides("outbounds[1].tag=proxy")
+//	got, err := jsons.Merge("config.json", layer)
+//
+// Unlike other inputs, the values are not merged, but set like the "add"
+// and "replace" operations of JSON Patch:
+//
+//   - Arrays and objects replace the existing values instead of being
+//     merged into them, and null is set as is, even with
+//     WithMergePatchSemantics.
+//   - A value on the path which is not an object or array is replaced by
+//     a new object or array, without ErrTypeMismatch, e.g.: "a.b=1"
+//     turns {"a":"str"} into {"a":{"b":1}}. So is a value of another type
+//     at the path.
+//   - Merge directives in values are not processed, and the replaced
+//     values are not reported by WithStrictConflicts.
+func ParseOverrides(pairs ...string) (*Overrides, error) {
+	o := &Overrides{}
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid override %q: missing '='", pair)
+		}
+		path, err := parseOverridePath(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid override %q: %w", pair, err)
+		}
+		o.values = append(o.values, overrideValue{Path: path, Value: value})
+	}
+	return o, nil
+}
+
+// EnvOverrides parses environment variables with the prefix into a layer
+// like ParseOverrides does, where the prefix and the keys are separated
+// by "__", e.g.:
+//
+//	APP__LOG__LEVEL=debug     sets "level" of "log" to "debug"
+//
+// Since variable names are usually uppercase, keys match the existing
+// keys ignoring case, e.g.: APP__API__LISTENPORT sets "listenPort" of
+// "api" if it exists, and the missing keys are created in lowercase.
+//
+// Array indexes are not supported in variable names. The variables are
+// read from environ in the form of "key=value", or os.Environ if nil.
+func EnvOverrides(prefix string, environ []string) (*Overrides, error) {
+	if environ == nil {
+		environ = os.Environ()
+	}
+	prefix += "__"
+	var vars []string
+	for _, env := range environ {
+		if strings.HasPrefix(env, prefix) {
+			vars = append(vars, env)
+		}
+	}
+	// in a deterministic order
+	sort.Strings(vars)
+	o := &Overrides{}
+	for _, env := range vars {
+		name, value, _ := strings.Cut(env, "=")
+		var path []overrideKey
+		for _, key := range strings.Split(strings.TrimPrefix(name, prefix), "__") {
+			if key == "" {
+				return nil, fmt.Errorf("invalid override %q: empty key", name)
+			}
+			path = append(path, overrideKey{Key: strings.ToLower(key), Fold: true})
+		}
+		o.values = append(o.values, overrideValue{Path: path, Value: value})
+	}
+	return o, nil
+}
+
+// overrideKey is a key of object, or an index of array if IsIndex
+type overrideKey struct {
+	Key     string
+	Index   int
+	IsIndex bool
+	// Fold makes Key match the existing keys ignoring case
+	Fold bool
+}
+
+// name returns the key of m which k refers to, or k.Key if not found
+func (k overrideKey) name(m *ordered.Map) string {
+	if _, ok := m.Values[k.Key]; ok || !k.Fold {
+		return k.Key
+	}
+	for _, key := range m.Keys {
+		if strings.EqualFold(key, k.Key) {
+			return key
+		}
+	}
+	return k.Key
+}
+
+// parseOverridePath parses paths like "a.b[0].c"
+func parseOverridePath(s string) ([]overrideKey, error) {
+	var path []overrideKey
+	for _, part := range strings.Split(s, ".") {
+		key := part
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			key = part[:i]
+		}
+		if key == "" {
+			return nil, fmt.Errorf("empty key")
+		}
+		if strings.IndexByte(key, ']') >= 0 {
+			return nil, fmt.Errorf("invalid key: %s", key)
+		}
+		path = append(path, overrideKey{Key: key})
+		rest := part[len(key):]
+		for rest != "" {
+			end := strings.IndexByte(rest, ']')
+			if rest[0] != '[' || end < 0 {
+				return nil, fmt.Errorf("invalid index: %s", rest)
+			}
+			index, err := parseOverrideIndex(rest[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid index: %s", rest[:end+1])
+			}
+			path = append(path, overrideKey{Index: index, IsIndex: true})
+			rest = rest[end+1:]
+		}
+	}
+	return path, nil
+}
+
+// parseOverrideIndex parses s as an array index, which is "0"
+// or digits without a leading zero.
+func parseOverrideIndex(s string) (int, error) {
+	if s == "" || (len(s) > 1 && s[0] == '0') {
+		return 0, strconv.ErrSyntax
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return 0, strconv.ErrSyntax
+		}
+	}
+	return strconv.Atoi(s)
+}
+
+// parseOverrideValue parses s as a JSON literal if valid, otherwise
+// a string, where numbers are json.Number if useNumber.
+func parseOverrideValue(s string, useNumber bool) interface{} {
+	if !json.Valid([]byte(s)) {
+		return s
+	}
+	// decode with a wrapper to keep the order of object fields
+	wrapper := ordered.New()
+	opts := ordered.DecodeOptions{UseNumber: useNumber}
+	if err := wrapper.UnmarshalJSONWith([]byte(`{"v":`+s+`}`), opts); err != nil {
+		return s
+	}
+	return wrapper.Values["v"]
+}
+
+// apply sets the values at their paths of target in order, where
+// numbers are json.Number if useNumber, and reports the changes
+// to the observer if not nil.
+func (o *Overrides) apply(target *ordered.Map, useNumber bool, observer patch.Observer) error {
+	for _, v := range o.values {
+		op, err := v.operation(target, parseOverrideValue(v.Value, useNumber))
+		if err != nil {
+			return err
+		}
+		if err := (patch.Patch{op}).ApplyObserved(target, observer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// operation returns the patch operation which sets value at the path
+// of v in target, the missing containers on the path are added with it.
+func (v *overrideValue) operation(target *ordered.Map, value interface{}) (*patch.Operation, error) {
+	var (
+		node interface{} = target
+		path []string
+	)
+	for i, key := range v.Path {
+		switch n := node.(type) {
+		case *ordered.Map:
+			if !key.IsIndex {
+				name := key.name(n)
+				child, ok := n.Values[name]
+				if !ok {
+					return newOverrideOperation("add", pointer.Append(path, name), v.Path[i+1:], value)
+				}
+				node, path = child, pointer.Append(path, name)
+				continue
+			}
+		case []interface{}:
+			if key.IsIndex {
+				index := strconv.Itoa(key.Index)
+				switch {
+				case key.Index == len(n):
+					return newOverrideOperation("add", pointer.Append(path, index), v.Path[i+1:], value)
+				case key.Index > len(n):
+					return nil, &MergeError{
+						Path: pointer.Format(path),
+						Err:  fmt.Errorf("index %d out of range, the length is %d", key.Index, len(n)),
+					}
+				}
+				node, path = n[key.Index], pointer.Append(path, index)
+				continue
+			}
+		}
+		// the existing value is not the container of key
+		return newOverrideOperation("replace", path, v.Path[i:], value)
+	}
+	return patch.NewOperation("replace", path, value), nil
+}
+
+// newOverrideOperation returns the operation op at path, whose value
+// holds value at the rest path, where the arrays are newly created.
+func newOverrideOperation(op string, path []string, rest []overrideKey, value interface{}) (*patch.Operation, error) {
+	for i := len(rest) - 1; i >= 0; i-- {
+		key := rest[i]
+		if !key.IsIndex {
+			m := ordered.New()
+			m.Set(key.Key, value)
+			value = m
+			continue
+		}
+		if key.Index != 0 {
+			return nil, &MergeError{
+				Path: pointer.Format(path),
+				Err:  fmt.Errorf("index %d out of range of the new array", key.Index),
+			}
+		}
+		value = []interface{}{value}
+	}
+	return patch.NewOperation(op, path, value), nil
+}
@@ -0,0 +1,166 @@
+package jsons_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/qjebbs/go-jsons"
+)
+
+func TestParseOverrides(t *testing.T) {
+	layer, err := jsons.ParseOverrides(
+		"log.level=debug",
+		"log.access=",
+		"inbounds[1].port=1080",
+		"inbounds[1].sniffing=true",
+		"dns.servers=[\"1.1.1.1\"]",
+		"api={\"z\":1,\"a\":null}",
+		"matrix[0][1]=1.5",
+		"tag=\"123\"",
+		"id=123",
+		"text=hello, world",
+		"log.level=info",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := []byte(`{"log":{"level":"error"},"inbounds":[{"port":1},{"port":2,"listen":"::"}],"dns":{"servers":["8.8.8.8"]},"matrix":[[0,0]]}`)
+	got, err := jsons.Merge(base, layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"log":{"level":"info","access":""},"inbounds":[{"port":1},{"port":1080,"listen":"::","sniffing":true}],"dns":{"servers":["1.1.1.1"]},"matrix":[[0,1.5]],"api":{"z":1,"a":null},"tag":"123","id":123,"text":"hello, world"}`
+	if string(got) != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestParseOverridesError(t *testing.T) {
+	for _, pair := range []string{
+		"log.level",
+		"=1",
+		"a..b=1",
+		"[0]=1",
+		"a[x]=1",
+		"a[-1]=1",
+		"a[0=1",
+		"a[0]b=1",
+		"a.[0]=1",
+		"a.=1",
+		"a[+1]=1",
+		"a[01]=1",
+		"a[]=1",
+		"a]=1",
+	} {
+		_, err := jsons.ParseOverrides(pair)
+		if err == nil {
+			t.Errorf("%s: want error, got nil", pair)
+		}
+	}
+}
+
+func TestEnvOverrides(t *testing.T) {
+	layer, err := jsons.EnvOverrides("APP", []string{
+		"HOME=/root",
+		"APP__LOG__LEVEL=debug",
+		"APP__API__PORT=8080",
+		"APPLICATION=x",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := jsons.Merge(layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"api":{"port":8080},"log":{"level":"debug"}}`; string(got) != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+	// keys match the existing ones ignoring case
+	layer, err = jsons.EnvOverrides("APP", []string{"APP__API__LISTENPORT=8080", "APP__API__LOGLEVEL=debug"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = jsons.Merge([]byte(`{"api":{"listenPort":80}}`), layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"api":{"listenPort":8080,"loglevel":"debug"}}`; string(got) != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+	_, err = jsons.EnvOverrides("APP", []string{"APP__LOG____LEVEL=debug"})
+	if err == nil {
+		t.Error("want error, got nil")
+	}
+	t.Setenv("JSONS_TEST__LOG__LEVEL", "error")
+	layer, err = jsons.EnvOverrides("JSONS_TEST", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = jsons.Merge(layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"log":{"level":"error"}}`; string(got) != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
+
+func TestMergeOverrides(t *testing.T) {
+	flags, err := jsons.ParseOverrides("outbounds[1].tag=proxy", "log.level=debug", "outbounds[3].tag=new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := jsons.EnvOverrides("APP", []string{"APP__LOG__LEVEL=error"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := []byte(`{"log":{"level":"info"},"outbounds":[{"tag":"direct"},{"tag":"block"},{"tag":"dns"}]}`)
+	// with the default options, where arrays are appended
+	m := jsons.NewMerger()
+	got, prov, err := m.MergeWithProvenance(base, env, flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"log":{"level":"debug"},"outbounds":[{"tag":"direct"},{"tag":"proxy"},{"tag":"dns"},{"tag":"new"}]}`
+	if string(got) != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+	if prov["/log/level"] != "inputs[2]" || prov["/outbounds/1/tag"] != "inputs[2]" ||
+		prov["/outbounds/0/tag"] != "inputs[0]" || prov["/outbounds/3/tag"] != "inputs[2]" {
+		t.Errorf("unexpected provenance: %v", prov)
+	}
+	// the layer is not modified by merging
+	got, err = m.MergeAs(jsons.FormatJSON, base, flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+	// never padded with null
+	for _, pair := range []string{"outbounds[4].tag=x", "inbounds[1].tag=x", "log.level[1]=x"} {
+		layer, err := jsons.ParseOverrides(pair)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = m.Merge(base, layer)
+		var me *jsons.MergeError
+		if !errors.As(err, &me) || me.Input != "inputs[1]" {
+			t.Errorf("%s: want *jsons.MergeError of inputs[1], got %v", pair, err)
+		}
+	}
+	// a non-container value on the path is replaced
+	layer, err := jsons.ParseOverrides("log.level.min=warn", "inbounds[0].port=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = m.Merge(base, layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `{"log":{"level":{"min":"warn"}},"outbounds":[{"tag":"direct"},{"tag":"block"},{"tag":"dns"}],"inbounds":[{"port":1}]}`
+	if string(got) != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}